@@ -0,0 +1,85 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+)
+
+// AudioOffset returns the offset at which the audio of r begins, i.e. the
+// length of the ID3v2 tag at its front, or 0 if there is none. The position
+// of r is restored before returning.
+func AudioOffset(r io.ReadSeeker) (int64, error) {
+	origin, err := r.Seek(0, 1)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Seek(origin, 0)
+
+	if _, err := r.Seek(0, 0); err != nil {
+		return 0, err
+	}
+	return TagSize(r)
+}
+
+// Returns the bounds of the audio in r, between the ID3v2 tag at the front
+// and any tags at the end.
+func audioRange(r io.ReadSeeker) (start int64, end int64, err error) {
+	start, err = AudioOffset(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	l, err := scanTail(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	if l.end < start {
+		return 0, 0, fmt.Errorf("ID3v2 tag overruns the audio: %d/%d bytes", start, l.end)
+	}
+	return start, l.end, nil
+}
+
+// AudioHash returns the SHA-256 of the audio in r alone, leaving out the
+// ID3v2 tag at the front and any ID3v1, Lyrics3, APE or appended ID3v2 tags
+// at the end, so that copies of a file tagged differently hash the same.
+func AudioHash(r io.ReadSeeker) ([]byte, error) {
+	start, end, err := audioRange(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(start, 0); err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(h, r, end-start); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// CheckAudioSize compares DeclaredAudioSize with the size of the audio in
+// r, returning the actual size and whether they match. A mismatch suggests
+// the file was truncated or had data appended. ok is always true when
+// there is no declared size.
+func (t *SimpleTags) CheckAudioSize(r io.ReadSeeker) (actual int64, ok bool, err error) {
+	start, end, err := audioRange(r)
+	if err != nil {
+		return 0, false, err
+	}
+	actual = end - start
+	return actual, t.DeclaredAudioSize == 0 || int64(t.DeclaredAudioSize) == actual, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAudioHash(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	files := [][]byte{
+		audio,
+		append(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title"))), audio...),
+		append(append(buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Other"))), audio...), buildID3v1Tag("Title")...),
+		append(append(append([]byte{}, audio...), buildAPETag("Title", "APE")...), buildID3v1Tag("Title")...),
+	}
+
+	expected, err := AudioHash(bytes.NewReader(files[0]))
+	if err != nil {
+		t.Fatalf("AudioHash: %s", err)
+	}
+	for i, file := range files[1:] {
+		h, err := AudioHash(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("%d: AudioHash: %s", i+1, err)
+		}
+		if !bytes.Equal(h, expected) {
+			t.Errorf("%d: expected %x got %x", i+1, expected, h)
+		}
+	}
+
+	other := append([]byte{}, files[1]...)
+	other[len(other)-1] ^= 0xff
+	if h, _ := AudioHash(bytes.NewReader(other)); bytes.Equal(h, expected) {
+		t.Errorf("expected different audio to hash differently")
+	}
+}
+
+func TestCheckAudioSize(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	tests := []struct {
+		tsiz string
+		ok   bool
+	}{
+		{"256", true},
+		{"512", false},
+		{"", true},
+	}
+	for _, test := range tests {
+		tag := buildID3v2Tag(3,
+			buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+			buildID3v2Frame(3, "TSIZ", []byte("\x00"+test.tsiz)))
+		r := bytes.NewReader(append(append(tag, audio...), buildID3v1Tag("Title")...))
+		f, err := Read(r)
+		if err != nil {
+			t.Fatalf("%q: Read: %s", test.tsiz, err)
+		}
+		actual, ok, err := f.CheckAudioSize(r)
+		if err != nil {
+			t.Fatalf("%q: CheckAudioSize: %s", test.tsiz, err)
+		}
+		if actual != int64(len(audio)) || ok != test.ok {
+			t.Errorf("%q: expected %d, %t got %d, %t", test.tsiz, len(audio), test.ok, actual, ok)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Diff returns the exported fields of a and b that differ, with a's value
+// first. Fields are keyed by name, with slices and maps compared element
+// by element and structs field by field, e.g. "Artists[1]",
+// "UserText[replaygain_track_gain]" or "Pictures[0].Description". An
+// element missing from one side is reported against "". Binary data such
+// as picture data is described by its length. A nil SimpleTags is treated
+// as an empty one.
+func Diff(a, b *SimpleTags) map[string][2]string {
+	if a == nil {
+		a = &SimpleTags{}
+	}
+	if b == nil {
+		b = &SimpleTags{}
+	}
+	d := map[string][2]string{}
+	diffValues(d, "", reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem())
+	return d
+}
+
+// Adds the differences between a and b, of the same type, to d. A value
+// that is missing from one side is invalid.
+func diffValues(d map[string][2]string, path string, a, b reflect.Value) {
+	if !a.IsValid() && !b.IsValid() {
+		return
+	}
+	var t reflect.Type
+	if a.IsValid() {
+		t = a.Type()
+	} else {
+		t = b.Type()
+	}
+
+	switch {
+	case t == timeType:
+		ta, tb := formatDiffTime(a), formatDiffTime(b)
+		if ta != tb {
+			d[path] = [2]string{ta, tb}
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		if !a.IsValid() || !b.IsValid() || !bytes.Equal(a.Bytes(), b.Bytes()) {
+			d[path] = [2]string{formatDiffBytes(a), formatDiffBytes(b)}
+		}
+	case t.Kind() == reflect.Ptr:
+		diffValues(d, path, elemOrInvalid(a), elemOrInvalid(b))
+	case t.Kind() == reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := f.Name
+			if path != "" {
+				name = path + "." + name
+			}
+			diffValues(d, name, fieldOrInvalid(a, i), fieldOrInvalid(b, i))
+		}
+	case t.Kind() == reflect.Slice:
+		n := 0
+		if a.IsValid() {
+			n = a.Len()
+		}
+		if b.IsValid() && b.Len() > n {
+			n = b.Len()
+		}
+		for i := 0; i < n; i++ {
+			diffValues(d, fmt.Sprintf("%s[%d]", path, i), indexOrInvalid(a, i), indexOrInvalid(b, i))
+		}
+	case t.Kind() == reflect.Map:
+		keys := map[interface{}]reflect.Value{}
+		for _, m := range []reflect.Value{a, b} {
+			if m.IsValid() {
+				for _, k := range m.MapKeys() {
+					keys[k.Interface()] = k
+				}
+			}
+		}
+		for _, k := range keys {
+			diffValues(d, fmt.Sprintf("%s[%v]", path, k), mapIndexOrInvalid(a, k), mapIndexOrInvalid(b, k))
+		}
+	default:
+		va, vb := formatDiffValue(a), formatDiffValue(b)
+		if va != vb {
+			d[path] = [2]string{va, vb}
+		}
+	}
+}
+
+func elemOrInvalid(v reflect.Value) reflect.Value {
+	if !v.IsValid() || v.IsNil() {
+		return reflect.Value{}
+	}
+	return v.Elem()
+}
+
+func fieldOrInvalid(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+	return v.Field(i)
+}
+
+func indexOrInvalid(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() || i >= v.Len() {
+		return reflect.Value{}
+	}
+	return v.Index(i)
+}
+
+// MapIndex returns the invalid Value for missing keys.
+func mapIndexOrInvalid(v reflect.Value, k reflect.Value) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+	return v.MapIndex(k)
+}
+
+func formatDiffValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func formatDiffTime(v reflect.Value) string {
+	if !v.IsValid() || v.Interface().(time.Time).IsZero() {
+		return ""
+	}
+	return v.Interface().(time.Time).Format(time.RFC3339)
+}
+
+func formatDiffBytes(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("%d bytes", v.Len())
+}
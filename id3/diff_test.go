@@ -0,0 +1,61 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	a := &SimpleTags{
+		Header:     &ID3v2Header{Version: 3},
+		Title:      "Title",
+		Artists:    []string{"Daft Punk", "Pharrell"},
+		Pictures:   []Picture{{"image/png", PictureTypeFrontCover, "Front", []byte("png")}},
+		UserText:   map[string]string{"replaygain_track_gain": "-6.50 dB", "MB": "1"},
+		RecordedAt: time.Date(2013, 5, 17, 0, 0, 0, 0, time.UTC),
+		Ownership:  &Ownership{Price: "USD9.99"},
+	}
+	b := &SimpleTags{
+		Header:   &ID3v2Header{Version: 4},
+		Title:    "Title",
+		Artists:  []string{"Daft Punk", "Pharrell Williams", "Nile Rodgers"},
+		Pictures: []Picture{{"image/png", PictureTypeFrontCover, "Cover", []byte("png!")}},
+		UserText: map[string]string{"replaygain_track_gain": "-7.00 dB", "MB": "1"},
+	}
+
+	expected := map[string][2]string{
+		"Header.Version":                  {"3", "4"},
+		"Artists[1]":                      {"Pharrell", "Pharrell Williams"},
+		"Artists[2]":                      {"", "Nile Rodgers"},
+		"Pictures[0].Description":         {"Front", "Cover"},
+		"Pictures[0].Data":                {"3 bytes", "4 bytes"},
+		"UserText[replaygain_track_gain]": {"-6.50 dB", "-7.00 dB"},
+		"RecordedAt":                      {"2013-05-17T00:00:00Z", ""},
+		"Ownership.Price":                 {"USD9.99", ""},
+	}
+	if d := Diff(a, b); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %q got %q", expected, d)
+	}
+
+	if d := Diff(a, a); len(d) != 0 {
+		t.Errorf("expected no differences got %q", d)
+	}
+	if d := Diff(nil, &SimpleTags{Title: "Title"}); !reflect.DeepEqual(d, map[string][2]string{"Title": {"", "Title"}}) {
+		t.Errorf("nil: got %q", d)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExplainFrame annotates the byte layout of a frame body for debugging,
+// e.g. "[enc=0x03][desc 'foo'\0][value 'bar']". Text, TXXX and COMM frames
+// of any version are broken down into their parts; the bodies of other
+// frames are only measured.
+func ExplainFrame(id string, data []byte) string {
+	var name string
+	for _, m := range []map[string]string{ID3v24Tags, ID3v23Tags, ID3v22Tags} {
+		if n, ok := m[id]; ok {
+			name = n
+			break
+		}
+	}
+
+	var b strings.Builder
+	switch {
+	case len(data) == 0:
+		return "[empty]"
+	case name == "usertext":
+		encoding, rest := explainEncoding(&b, data)
+		rest = explainString(&b, "desc", encoding, rest)
+		explainString(&b, "value", encoding, rest)
+	case name == "comments":
+		encoding, rest := explainEncoding(&b, data)
+		if len(rest) < 3 {
+			fmt.Fprintf(&b, "[truncated %d bytes]", len(rest))
+			break
+		}
+		fmt.Fprintf(&b, "[lang '%s']", rest[:3])
+		rest = explainString(&b, "desc", encoding, rest[3:])
+		explainString(&b, "text", encoding, rest)
+	case name != "" && id[0] == 'T':
+		// ID3v2.4 text frames may hold several terminated values
+		encoding, rest := explainEncoding(&b, data)
+		for len(rest) > 0 {
+			rest = explainString(&b, "text", encoding, rest)
+		}
+	default:
+		fmt.Fprintf(&b, "[data %d bytes]", len(data))
+	}
+	return b.String()
+}
+
+// Writes the encoding byte at the front of data and returns it along with
+// the rest of data.
+func explainEncoding(b *strings.Builder, data []byte) (byte, []byte) {
+	fmt.Fprintf(b, "[enc=0x%02x]", data[0])
+	return data[0], data[1:]
+}
+
+// Writes the string at the front of data, marking its terminator if it has
+// one, and returns what follows it.
+func explainString(b *strings.Builder, label string, encoding byte, data []byte) []byte {
+	str, rest := splitID3v2String(encoding, data)
+	if s, err := parseID3v2EncodedString(encoding, str); err != nil {
+		fmt.Fprintf(b, "[%s: %d undecodable bytes", label, len(str))
+	} else {
+		fmt.Fprintf(b, "[%s '%s'", label, s)
+	}
+	if rest != nil {
+		b.WriteString(strings.Repeat(`\0`, len(id3v2Terminator(encoding))))
+	}
+	b.WriteString("]")
+	return rest
+}
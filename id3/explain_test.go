@@ -0,0 +1,38 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import "testing"
+
+func TestExplainFrame(t *testing.T) {
+	tests := []struct {
+		id       string
+		data     string
+		expected string
+	}{
+		{"TXXX", "\x03foo\x00bar", `[enc=0x03][desc 'foo'\0][value 'bar']`},
+		{"TXX", "\x00foo\x00bar", `[enc=0x00][desc 'foo'\0][value 'bar']`},
+		{"COMM", "\x01eng\xff\xfe\x00\x00\xff\xfeh\x00i\x00", `[enc=0x01][lang 'eng'][desc ''\0\0][text 'hi']`},
+		{"TPE1", "\x03A\x00B\x00", `[enc=0x03][text 'A'\0][text 'B'\0]`},
+		{"TIT2", "\x02\x00B\x00j\x00\xf6", "[enc=0x02][text 'Bjö']"},
+		{"APIC", "\x00image/png\x00\x03\x00\x89PNG", "[data 17 bytes]"},
+		{"TIT2", "", "[empty]"},
+	}
+	for _, test := range tests {
+		if s := ExplainFrame(test.id, []byte(test.data)); s != test.expected {
+			t.Errorf("%s %q: expected %s got %s", test.id, test.data, test.expected, s)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Splits a "N/M" position such as those found in TRCK and TPOS frames.
+// "N of M" is accepted too and whitespace around either part is ignored.
+// A missing or non-numeric part is returned as 0, and an error is only
+// returned when neither part is numeric.
+func splitPosition(s string) (n int, total int, err error) {
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 1 {
+		if i := strings.Index(strings.ToLower(s), "of"); i >= 0 {
+			parts = []string{s[:i], s[i+2:]}
+		}
+	}
+
+	n, nErr := strconv.Atoi(strings.TrimSpace(parts[0]))
+	totalErr := nErr
+	if len(parts) == 2 {
+		total, totalErr = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	if nErr != nil && totalErr != nil {
+		return 0, 0, fmt.Errorf("invalid position: %q", s)
+	}
+	return n, total, nil
+}
+
+// Parses a non-negative decimal number, ignoring surrounding whitespace.
+// Leading zeros are allowed so that "007" is 7.
+func parseNumber(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+// Splits the year from the front of a copyright or produced notice, which
+// starts with a 4 digit year and a space.
+func splitNoticeYear(s string) (year int, text string) {
+	if len(s) > 5 && s[4] == ' ' {
+		if year, ok := parseNumber(s[:4]); ok {
+			return year, strings.TrimSpace(s[5:])
+		}
+	}
+	return 0, s
+}
+
+// YearInt returns Year as an integer. Only a 4 digit, non-zero year is
+// accepted, optionally followed by the rest of an ID3v2.4 timestamp such as
+// "2008-03-15". Returns ok=false for anything else, e.g. "0000" or "  ".
+func (t *SimpleTags) YearInt() (year int, ok bool) {
+	s := strings.TrimSpace(t.Year)
+	if len(s) > 4 && (s[4] == '-' || s[4] == 'T') {
+		s = s[:4]
+	}
+	if len(s) != 4 {
+		return 0, false
+	}
+	year, ok = parseNumber(s)
+	return year, ok && year > 0
+}
+
+// TrackNumber returns the track number from Track, ignoring any total, e.g.
+// 3 for "03/12". Returns ok=false unless the number is positive.
+func (t *SimpleTags) TrackNumber() (track int, ok bool) {
+	n, _, err := splitPosition(t.Track)
+	return n, err == nil && n > 0
+}
+
+// BPMInt returns BPM as an integer.
+func (t *SimpleTags) BPMInt() (bpm int, ok bool) {
+	return parseNumber(t.BPM)
+}
+
+// LengthMillis returns Length, which TLEN holds in milliseconds, as an
+// integer.
+func (t *SimpleTags) LengthMillis() (ms int, ok bool) {
+	return parseNumber(t.Length)
+}
+
+// JoinedArtists returns Artists separated by "; ", or Artist when there
+// are none.
+func (t *SimpleTags) JoinedArtists() string {
+	if len(t.Artists) == 0 {
+		return t.Artist
+	}
+	return strings.Join(t.Artists, "; ")
+}
+
+// JoinedGenres returns Genres separated by "; ", or Genre when there are
+// none.
+func (t *SimpleTags) JoinedGenres() string {
+	if len(t.Genres) == 0 {
+		return t.Genre
+	}
+	return strings.Join(t.Genres, "; ")
+}
+
+// SetGenre sets Genre from a numeric ID3v1 code such as "17", the "(17)"
+// form used by ID3v2 or a genre name. Names of known genres are stored in
+// the table's capitalisation so that "rock" becomes "Rock".
+func (t *SimpleTags) SetGenre(s string) {
+	genre := convertID3v1Genre(strings.TrimSpace(s), id3v1Genres)
+	for _, g := range id3v1Genres {
+		if strings.EqualFold(g, genre) {
+			genre = g
+			break
+		}
+	}
+	t.Genre = genre
+}
+
+// Looks up a TXXX value by description, ignoring case.
+func (t *SimpleTags) userText(desc string) (string, bool) {
+	if v, ok := t.UserText[desc]; ok {
+		return v, true
+	}
+	for k, v := range t.UserText {
+		if strings.EqualFold(k, desc) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// ResolveTrackTotal returns the total number of tracks, taken from the "/N"
+// part of the track number or else a "TOTALTRACKS" TXXX frame. Returns 0 if
+// the total is unknown.
+func (t *SimpleTags) ResolveTrackTotal() int {
+	if _, total, _ := splitPosition(t.Track); total > 0 {
+		return total
+	}
+	if v, ok := t.userText("TOTALTRACKS"); ok {
+		if total, err := strconv.Atoi(strings.TrimSpace(v)); err == nil && total > 0 {
+			return total
+		}
+	}
+	return 0
+}
+
+// Conflicts returns the fields on which the ID3v1 and ID3v2 tags disagree,
+// keyed like Map, with the ID3v1 value first. Differences the ID3v1 format
+// forces, such as truncation or a missing track total, aren't conflicts.
+func (t *SimpleTags) Conflicts() map[string][2]string {
+	return t.conflicts
+}
+
+// Reports whether an ID3v1 value agrees with the ID3v2 value of the field.
+// Empty ID3v1 fields agree with anything.
+func id3v1Matches(key, v1, v2 string) bool {
+	v1, v2 = strings.TrimSpace(v1), strings.TrimSpace(v2)
+	switch {
+	case v1 == "" || v1 == v2:
+		return true
+	case key == "track":
+		n1, _, _ := splitPosition(v1)
+		n2, _, _ := splitPosition(v2)
+		return n1 == n2
+	case key == "year":
+		return len(v2) > 4 && v1 == v2[:4]
+	case key == "genre":
+		// genres without an ID3v1 code are written as 255
+		return v1 == "Unspecified"
+	}
+	// fields are cut to 30 bytes
+	return len(v1) >= 28 && strings.HasPrefix(v2, v1)
+}
+
+// Map returns the non-empty fields of t keyed by their lowercase names, in
+// the style of the map returned by ReadFile. The "comment" key holds
+// Comment, or else the first comment without a description.
+func (t *SimpleTags) Map() map[string]string {
+	m := map[string]string{}
+	set := func(k, v string) {
+		if v != "" {
+			m[k] = v
+		}
+	}
+
+	set("title", t.Title)
+	set("artist", t.Artist)
+	set("album", t.Album)
+	set("year", t.Year)
+	set("track", t.Track)
+	set("disc", t.Disc)
+	set("genre", t.Genre)
+	set("length", t.Length)
+	set("publisher", t.Publisher)
+	set("bpm", t.BPM)
+	set("mood", t.Mood)
+	set("composer", t.Composer)
+	set("copyright", t.Copyright)
+	set("encodedby", t.EncodedBy)
+	set("encoder", t.Encoder)
+	set("language", t.Language)
+	set("writer", t.Writer)
+	set("originalartist", t.OriginalArtist)
+	set("media", t.Media)
+	set("podcastid", t.PodcastID)
+	set("podcastfeed", t.PodcastFeed)
+	set("podcastdescription", t.PodcastDescription)
+	set("keywords", t.Keywords)
+	if !t.OriginalRelease.IsZero() {
+		set("originalrelease", formatID3v2Timestamp(t.OriginalRelease))
+	}
+	set("releasetime", t.ReleaseTime)
+
+	set("comment", t.mainComment())
+	return m
+}
+
+// Returns Comment, or else the first comment without a description.
+func (t *SimpleTags) mainComment() string {
+	if t.Comment != "" {
+		return t.Comment
+	}
+	for _, c := range t.Comments {
+		if c.Description == "" {
+			return c.Text
+		}
+	}
+	return ""
+}
+
+// Truncated returns a copy of t with each string field cut to at most n
+// runes, the last of which is an ellipsis when the field was shortened.
+// Slices, maps and pointers are shared with t.
+func (t *SimpleTags) Truncated(n int) *SimpleTags {
+	c := *t
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.String && f.CanSet() {
+			f.SetString(truncateRunes(f.String(), n))
+		}
+	}
+	return &c
+}
+
+// Shortens s to n runes, ending in an ellipsis if anything was cut.
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
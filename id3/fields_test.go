@@ -0,0 +1,333 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestSplitPosition(t *testing.T) {
+	tests := []struct {
+		s         string
+		n, total  int
+		expectErr bool
+	}{
+		{"3/12", 3, 12, false},
+		{" 3 / 12 ", 3, 12, false},
+		{"3of12", 3, 12, false},
+		{"3 of 12", 3, 12, false},
+		{"07", 7, 0, false},
+		{"/12", 0, 12, false},
+		{"A1", 0, 0, true},
+		{"", 0, 0, true},
+	}
+	for _, test := range tests {
+		n, total, err := splitPosition(test.s)
+		if n != test.n || total != test.total || (err != nil) != test.expectErr {
+			t.Errorf("%q: expected %d, %d, error %t got %d, %d, %v", test.s, test.n, test.total, test.expectErr, n, total, err)
+		}
+	}
+}
+
+func TestResolveTrackTotal(t *testing.T) {
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TRCK", []byte("\x005")),
+		buildID3v2Frame(3, "TXXX", []byte("\x00TOTALTRACKS\x0010")))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if total := f.ResolveTrackTotal(); total != 10 {
+		t.Errorf("TXXX total: expected 10 got %d", total)
+	}
+
+	tests := []struct {
+		tags  SimpleTags
+		total int
+	}{
+		{SimpleTags{Track: "5/12", UserText: map[string]string{"TOTALTRACKS": "10"}}, 12},
+		{SimpleTags{Track: "5", UserText: map[string]string{"totaltracks": " 9 "}}, 9},
+		{SimpleTags{Track: "5"}, 0},
+		{SimpleTags{}, 0},
+	}
+	for _, test := range tests {
+		if total := test.tags.ResolveTrackTotal(); total != test.total {
+			t.Errorf("%+v: expected %d got %d", test.tags, test.total, total)
+		}
+	}
+}
+
+func TestMap(t *testing.T) {
+	f := &SimpleTags{
+		Title:  "Title",
+		Artist: "Artist",
+		Year:   "2001",
+		Track:  "3/9",
+		Comments: []Comment{
+			{"eng", "iTunNORM", " 00000180"},
+			{"eng", "", "Note"},
+		},
+	}
+	expected := map[string]string{
+		"title":   "Title",
+		"artist":  "Artist",
+		"year":    "2001",
+		"track":   "3/9",
+		"comment": "Note",
+	}
+
+	m := f.Map()
+	if len(m) != len(expected) {
+		t.Errorf("expected %d keys got %d: %v", len(expected), len(m), m)
+	}
+	for k, v := range expected {
+		if m[k] != v {
+			t.Errorf("%s: expected '%s' got '%s'", k, v, m[k])
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	f := &SimpleTags{
+		Title:  "Pompeii Am Götterdämmerung",
+		Artist: "The Flaming Lips",
+		Album:  "ÄÖÜ",
+		Year:   "2006",
+	}
+
+	c := f.Truncated(13)
+	expected := map[string]string{
+		"Title":  "Pompeii Am G…",
+		"Artist": "The Flaming …",
+		"Album":  "ÄÖÜ",
+		"Year":   "2006",
+	}
+	actual := map[string]string{"Title": c.Title, "Artist": c.Artist, "Album": c.Album, "Year": c.Year}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("%s: expected '%s' got '%s'", k, v, actual[k])
+		}
+	}
+
+	if c = f.Truncated(2); c.Album != "Ä…" {
+		t.Errorf("Album: expected 'Ä…' got '%s'", c.Album)
+	}
+	if f.Title != "Pompeii Am Götterdämmerung" {
+		t.Errorf("original modified: '%s'", f.Title)
+	}
+	if c = f.Truncated(0); c.Title != "" {
+		t.Errorf("Truncated(0): expected '' got '%s'", c.Title)
+	}
+}
+
+func TestTruncatedEveryField(t *testing.T) {
+	// every exported string field must be truncated, including fields
+	// added after Truncated was written
+	f := &SimpleTags{}
+	v := reflect.ValueOf(f).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if fv := v.Field(i); fv.Kind() == reflect.String && fv.CanSet() {
+			fv.SetString("abcdef")
+		}
+	}
+
+	c := reflect.ValueOf(f.Truncated(3)).Elem()
+	for i := 0; i < c.NumField(); i++ {
+		if fv := c.Field(i); fv.Kind() == reflect.String && fv.CanSet() && fv.String() != "ab…" {
+			t.Errorf("%s: expected 'ab…' got '%s'", c.Type().Field(i).Name, fv.String())
+		}
+	}
+}
+
+func TestYearInt(t *testing.T) {
+	tests := []struct {
+		year     string
+		expected int
+		ok       bool
+	}{
+		{"2006", 2006, true},
+		{" 1997", 1997, true},
+		{"2008-03-15T21:30", 2008, true},
+		{"0000", 0, false},
+		{"    ", 0, false},
+		{"20O6", 0, false},
+		{"98", 0, false},
+		{"19999", 0, false},
+		{"", 0, false},
+	}
+	for _, test := range tests {
+		f := &SimpleTags{Year: test.year}
+		year, ok := f.YearInt()
+		if year != test.expected || ok != test.ok {
+			t.Errorf("%q: expected %d, %t got %d, %t", test.year, test.expected, test.ok, year, ok)
+		}
+	}
+
+	// Years from an ID3v1 tag are validated the same way.
+	tag := buildID3v1Tag("Title")
+	copy(tag[93:97], "0000")
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if _, ok := f.YearInt(); ok {
+		t.Errorf("ID3v1 year %q: expected ok=false", f.Year)
+	}
+}
+
+func TestJoinedArtistsAndGenres(t *testing.T) {
+	f := &SimpleTags{
+		Artist:  "Thom Yorke/Jonny Greenwood",
+		Artists: []string{"Thom Yorke", "Jonny Greenwood"},
+		Genre:   "Rock",
+		Genres:  []string{"Rock", "Electronic"},
+	}
+	if got := f.JoinedArtists(); got != "Thom Yorke; Jonny Greenwood" {
+		t.Errorf("JoinedArtists: expected %q got %q", "Thom Yorke; Jonny Greenwood", got)
+	}
+	if got := f.JoinedGenres(); got != "Rock; Electronic" {
+		t.Errorf("JoinedGenres: expected %q got %q", "Rock; Electronic", got)
+	}
+
+	// Without slices, e.g. for an ID3v1 tag, the scalar fields are used.
+	f = &SimpleTags{Artist: "Radiohead", Genre: "Rock"}
+	if got := f.JoinedArtists(); got != "Radiohead" {
+		t.Errorf("JoinedArtists: expected %q got %q", "Radiohead", got)
+	}
+	if got := f.JoinedGenres(); got != "Rock" {
+		t.Errorf("JoinedGenres: expected %q got %q", "Rock", got)
+	}
+}
+
+func TestSetGenre(t *testing.T) {
+	for _, s := range []string{"17", "(17)", "Rock", "rock", " (17) "} {
+		f := &SimpleTags{}
+		f.SetGenre(s)
+		if f.Genre != "Rock" {
+			t.Errorf("%q: expected 'Rock' got '%s'", s, f.Genre)
+		}
+	}
+
+	f := &SimpleTags{}
+	f.SetGenre("Vaporwave")
+	if f.Genre != "Vaporwave" {
+		t.Errorf("expected 'Vaporwave' got '%s'", f.Genre)
+	}
+}
+
+func TestNumericGetters(t *testing.T) {
+	tests := []struct {
+		tags     SimpleTags
+		get      func(*SimpleTags) (int, bool)
+		expected int
+		ok       bool
+	}{
+		{SimpleTags{Track: "07/12"}, (*SimpleTags).TrackNumber, 7, true},
+		{SimpleTags{Track: " 3 of 12 "}, (*SimpleTags).TrackNumber, 3, true},
+		{SimpleTags{Track: "/12"}, (*SimpleTags).TrackNumber, 0, false},
+		{SimpleTags{Track: "A1"}, (*SimpleTags).TrackNumber, 0, false},
+		{SimpleTags{BPM: " 0128 "}, (*SimpleTags).BPMInt, 128, true},
+		{SimpleTags{BPM: "128.5"}, (*SimpleTags).BPMInt, 0, false},
+		{SimpleTags{}, (*SimpleTags).BPMInt, 0, false},
+		{SimpleTags{Length: "215000"}, (*SimpleTags).LengthMillis, 215000, true},
+		{SimpleTags{Length: "-1"}, (*SimpleTags).LengthMillis, 0, false},
+	}
+	for i, test := range tests {
+		n, ok := test.get(&test.tags)
+		if n != test.expected || ok != test.ok {
+			t.Errorf("%d: expected %d, %t got %d, %t", i, test.expected, test.ok, n, ok)
+		}
+	}
+
+	// TBPM was looked up as "TBMP" in ID3v2.4 tags.
+	f, err := Read(bytes.NewReader(buildID3v2Tag(4, buildID3v2Frame(4, "TBPM", []byte("\x03120")))))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if bpm, ok := f.BPMInt(); bpm != 120 || !ok {
+		t.Errorf("TBPM: expected 120, true got %d, %t", bpm, ok)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	v1 := buildID3v1Tag("A Title Far Longer Than Thirty")
+	copy(v1[33:63], "Beatles")
+	copy(v1[63:93], "Abbey Road")
+	copy(v1[93:97], "1969")
+	v1[125], v1[126] = 0, 7
+	v1[127] = 0 // Blues
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00A Title Far Longer Than Thirty Characters")),
+		buildID3v2Frame(3, "TPE1", []byte("\x00The Beatles")),
+		buildID3v2Frame(3, "TALB", []byte("\x00Abbey Road")),
+		buildID3v2Frame(3, "TYER", []byte("\x001969")),
+		buildID3v2Frame(3, "TRCK", []byte("\x0007/17")),
+		buildID3v2Frame(3, "TCON", []byte("\x00Rock")))
+
+	f, err := Read(bytes.NewReader(append(tag, v1...)))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := map[string][2]string{
+		"artist": {"Beatles", "The Beatles"},
+		"genre":  {"Blues", "Rock"},
+	}
+	if c := f.Conflicts(); !reflect.DeepEqual(c, expected) {
+		t.Errorf("expected %q got %q", expected, c)
+	}
+
+	// without an ID3v1 tag there's nothing to disagree with
+	f, err = Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if c := f.Conflicts(); len(c) != 0 {
+		t.Errorf("expected no conflicts got %q", c)
+	}
+}
+
+func TestNoticeYear(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TCOP", []byte("\x032009 Example Records")),
+		buildID3v2Frame(4, "TPRO", []byte("\x032010 Example Productions")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.CopyrightYear != 2009 || f.CopyrightText != "Example Records" {
+		t.Errorf("copyright: expected 2009, 'Example Records' got %d, %q", f.CopyrightYear, f.CopyrightText)
+	}
+	if f.ProducedYear != 2010 || f.ProducedText != "Example Productions" {
+		t.Errorf("produced: expected 2010, 'Example Productions' got %d, %q", f.ProducedYear, f.ProducedText)
+	}
+
+	tests := map[string]struct {
+		year int
+		text string
+	}{
+		"Example Records":      {0, "Example Records"},
+		"2009":                 {0, "2009"},
+		"209x Example Records": {0, "209x Example Records"},
+		"":                     {0, ""},
+	}
+	for s, expected := range tests {
+		if year, text := splitNoticeYear(s); year != expected.year || text != expected.text {
+			t.Errorf("%q: expected %d, %q got %d, %q", s, expected.year, expected.text, year, text)
+		}
+	}
+}
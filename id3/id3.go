@@ -20,43 +20,617 @@
 package id3
 
 import (
+	"archive/tar"
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"reflect"
+	"time"
 )
 
+// ErrNoTags is returned when a stream contains neither ID3v2 frames nor an
+// ID3v1 tag.
+var ErrNoTags = errors.New("id3: no tags found")
+
+// ErrNotMP3 is returned when a stream has neither an ID3v2 header, an MPEG
+// frame sync nor an ID3v1 tag.
+var ErrNotMP3 = errors.New("id3: not an MP3 stream")
+
+// ErrUnsupportedVersion is returned when a stream's tag is of a version
+// excluded by Options.AcceptVersions.
+var ErrUnsupportedVersion = errors.New("id3: unsupported tag version")
+
+// ErrExperimental is returned when a stream's ID3v2 tag has the
+// experimental header flag set and Options.RejectExperimental is set.
+var ErrExperimental = errors.New("id3: tag marked experimental")
+
+// SimpleTags holds the ID3v2 header along with the most commonly used
+// fields. Fields missing from the ID3v2 tag are filled in from the ID3v1 tag.
+type SimpleTags struct {
+	Header   *ID3v2Header
+	Title    string
+	Artist   string
+	Album    string
+	Year     string
+	Track    string
+	Disc     string
+	Genre    string
+	Length   string
+	Comments []Comment
+	Pictures []Picture
+
+	// Comment is the main comment: the first COMM frame without a
+	// description or else the ID3v1 comment. Comments holds every COMM
+	// frame, including those with descriptions such as "iTunNORM" that
+	// applications use for their own data.
+	Comment string
+
+	// Lyrics holds the USLT (ULT in ID3v2.2) frames followed by the lyrics
+	// of a trailing Lyrics3 v2 tag.
+	Lyrics []UnsyncLyrics
+
+	// GenreRaw is the TCON (TCO in ID3v2.2) value as stored, e.g. "(17)",
+	// while Genre holds the genre name it resolves to.
+	GenreRaw string
+
+	// Genres holds every genre of the TCON frame: each code of an ID3v2.3
+	// value such as "(0)(2)Eurodisco" resolved to its name, followed by
+	// the refinement, or each value of an ID3v2.4 frame. Without a TCON
+	// frame it holds Genre, if any. JoinedGenres separates them with "; ".
+	Genres []string
+
+	// Artists holds each value of a TPE1 frame. ID3v2.4 separates
+	// multiple artists with nulls; Artist joins them with "/" and
+	// JoinedArtists with "; ".
+	Artists []string
+
+	Publisher string
+	BPM       string
+
+	// Podcast fields written by iTunes. WFED holds the feed URL but is
+	// encoded like a text frame.
+	PodcastID          string
+	PodcastFeed        string
+	PodcastDescription string
+	Keywords           string
+
+	// Mood is only defined for ID3v2.4 tags.
+	Mood string
+
+	// Text frames holding the composer (TCOM), the copyright notice (TCOP),
+	// who encoded the file (TENC) and with which software (TSSE), the
+	// languages sung (TLAN), the lyricist (TEXT), the original artist
+	// (TOPE) and the media the audio came from (TMED).
+	Composer       string
+	Copyright      string
+	EncodedBy      string
+	Encoder        string
+	Language       string
+	Writer         string
+	OriginalArtist string
+	Media          string
+
+	// Credits merges the musician (TMCL) and involved people (TIPL)
+	// lists, or the ID3v2.3 involved people list (IPLS).
+	Credits []Credit
+
+	// RecordedAt is Year parsed as an ID3v2.4 timestamp, in UTC, and
+	// RecordedPrecision tells which of its components were given. Both are
+	// zero if Year isn't a timestamp.
+	RecordedAt        time.Time
+	RecordedPrecision TimestampPrecision
+
+	// ReleaseTime is the release time (TDRL, ID3v2.4 only) as stored, and
+	// ReleasedAt and ReleasedPrecision are parsed from it like RecordedAt
+	// and RecordedPrecision from Year.
+	ReleaseTime       string
+	ReleasedAt        time.Time
+	ReleasedPrecision TimestampPrecision
+
+	// OriginalRelease is parsed from TDOR (TORY in ID3v2.3). Components
+	// finer than the timestamp's precision are left zero.
+	OriginalRelease time.Time
+
+	// UserText holds TXXX frames keyed by their description. The last
+	// frame wins when several share a description.
+	UserText map[string]string
+
+	Ownership  *Ownership
+	Commercial []Commercial
+
+	// RelativeVolume holds the master volume adjustment in dB of ID3v2.4
+	// RVA2 frames, keyed by their identification such as "track".
+	RelativeVolume map[string]float64
+
+	// Encrypted is set when an AENC frame (CRA in ID3v2.2) says the audio
+	// is encrypted.
+	Encrypted bool
+
+	// RawFrames holds the undecoded bodies of frames that are recognised
+	// but not parsed, such as AENC, keyed by frame ID.
+	RawFrames map[string][]byte
+
+	// URLs holds URL frames such as WOAR or WPUB keyed by frame ID.
+	URLs map[string]string
+
+	// UserURLs holds WXXX frames keyed by their description, apart from
+	// URLs so that a description can't clash with a frame ID. The last
+	// frame wins when several share a description.
+	UserURLs map[string]string
+
+	// TextFrames holds the value of every text frame, whether or not it has
+	// a field of its own, keyed by frame ID. See Frame.
+	TextFrames map[string]string
+
+	// DeclaredAudioSize is the size in bytes of the audio, excluding tags,
+	// according to TSIZ (TSI in ID3v2.2), or 0 if unknown. See
+	// CheckAudioSize.
+	DeclaredAudioSize int
+
+	// SeekOffset is the offset from the end of the tag to a supplementary
+	// tag given by an ID3v2.4 SEEK frame, or 0. Supplementary tags update
+	// the text fields and add their comments.
+	SeekOffset int64
+
+	// ExtendedHeader is the ID3v2 tag's extended header, or nil.
+	ExtendedHeader *ExtendedHeader
+
+	// The copyright (TCOP) and produced (TPRO, ID3v2.4 only) notices,
+	// e.g. "2009 Example Records", split into the year they start with and
+	// the rest. The year is 0 if the notice doesn't start with one.
+	CopyrightYear int
+	CopyrightText string
+	ProducedYear  int
+	ProducedText  string
+
+	// Speed, from 1 (slow) to 4 (hardcore), and the start and end times of
+	// the music as "mm:ss" are read from the rare enhanced ID3v1 "TAG+"
+	// block. Speed is 0 when unset.
+	Speed     int
+	StartTime string
+	EndTime   string
+
+	// Warnings describes problems with the tags that were worked around
+	// while reading them, such as frames skipped because they couldn't be
+	// decoded.
+	Warnings []string
+
+	// ID3v1 and ID3v2 values of fields on which the tags disagree.
+	conflicts map[string][2]string
+}
+
+// Options controls how tags are parsed. The zero value gives the behavior
+// of Read.
+type Options struct {
+	// StrictGenres limits ID3v1 genre codes to the 80 genres (0-79) of the
+	// ID3v1 specification instead of the Winamp extensions up to 191. Codes
+	// out of range resolve to "Unknown" in ID3v2 and "Unspecified" in ID3v1
+	// tags.
+	StrictGenres bool
+
+	// GenreTable replaces the English names of the ID3v1 genre codes, e.g.
+	// with translations. Entry n names code n; codes past the end of the
+	// table resolve as out of range.
+	GenreTable []string
+
+	// AcceptVersions limits parsing to the listed tag versions: 2, 3 or 4
+	// for the ID3v2 major versions and 1 for ID3v1. An ID3v2 tag of any
+	// other version fails with ErrUnsupportedVersion and an ID3v1 tag is
+	// ignored unless 1 is listed. Every version is accepted when empty.
+	AcceptVersions []int
+
+	// LenientFrameFlags accepts a final ID3v2.3 frame written without the 2
+	// flag bytes after its size, as some buggy encoders do, instead of
+	// failing on it.
+	LenientFrameFlags bool
+
+	// LenientEncoding reads frames whose text is declared as UTF-16 but
+	// starts with a UTF-8 byte order mark as UTF-8, as some encoders write
+	// them. Otherwise their text can't be decoded.
+	LenientEncoding bool
+
+	// CaseInsensitiveFrames accepts lowercase frame IDs such as "tit2",
+	// which some broken encoders write, reading them as their uppercase
+	// equivalents. A lowercase "id3" tag identifier is accepted as well.
+	CaseInsensitiveFrames bool
+
+	// ReadPastHeaderSize keeps reading frames beyond the tag size declared
+	// in the header, until padding, the end of the stream or something
+	// that isn't a frame, for tags whose encoder wrote too small a size.
+	// A warning is added to Warnings when frames were found past the
+	// declared size.
+	ReadPastHeaderSize bool
+
+	// BufferSize is the size of the buffer streams are read through. Sizes
+	// below the default of 4096 bytes are ignored.
+	BufferSize int
+
+	// RejectExperimental fails with ErrExperimental on ID3v2 tags with the
+	// experimental flag set in their header. Otherwise they are read as
+	// usual, with a warning added to Warnings.
+	RejectExperimental bool
+
+	// strict fails with a *Violation on frames that can't be decoded, as
+	// ReadStrict does, instead of skipping them with a warning.
+	strict bool
+}
+
+// Reports whether tags of the given version should be parsed.
+func (o *Options) accepts(version int) bool {
+	if len(o.AcceptVersions) == 0 {
+		return true
+	}
+	for _, v := range o.AcceptVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns BufferSize, or the default size when it's smaller.
+func (o *Options) bufferSize() int {
+	if o.BufferSize < 4096 {
+		return 4096
+	}
+	return o.BufferSize
+}
+
+// Returns the genre names that ID3v1 genre codes may refer to.
+func (o *Options) genres() []string {
+	genres := id3v1Genres
+	if o.GenreTable != nil {
+		genres = o.GenreTable
+	}
+	if o.StrictGenres && len(genres) > id3v1StandardGenres {
+		return genres[:id3v1StandardGenres]
+	}
+	return genres
+}
+
+// Read parses stream for ID3 information. The ID3v1 tag is only consulted
+// when reader is also an io.Seeker. If the stream has an ID3v2 header but no
+// frames and no ID3v1 tag, Read returns ErrNoTags along with a SimpleTags
+// holding only the header.
+func Read(reader io.Reader) (*SimpleTags, error) {
+	return ReadWithOptions(reader, Options{})
+}
+
+// ReadWithOptions is like Read but parses according to opts.
+func ReadWithOptions(reader io.Reader, opts Options) (*SimpleTags, error) {
+	tags, text, err := readTags(reader, &opts)
+	if err != nil {
+		if err == ErrNoTags && tags.Header != nil {
+			return tags, err
+		}
+		return nil, err
+	}
+	fillSimpleTags(tags, text)
+	return tags, nil
+}
+
+// Sets the text fields of tags from the text frames keyed by their names in
+// the ID3v2 tag maps.
+func fillSimpleTags(tags *SimpleTags, text map[string]string) {
+	tags.Title = text["title"]
+	tags.Artist = text["artist"]
+	tags.Album = text["album"]
+	tags.Year = text["year"]
+	tags.RecordedAt, tags.RecordedPrecision, _ = parseID3v2TimestampPrecision(tags.Year)
+	tags.ReleaseTime = text["releasetime"]
+	tags.ReleasedAt, tags.ReleasedPrecision, _ = parseID3v2TimestampPrecision(tags.ReleaseTime)
+	tags.Track = text["track"]
+	tags.Disc = text["disc"]
+	tags.Genre = text["genre"]
+	if len(tags.Genres) == 0 && tags.Genre != "" {
+		tags.Genres = []string{tags.Genre}
+	}
+	tags.Length = text["length"]
+	tags.Comment = text["comments"]
+	if tags.Comment == "" {
+		tags.Comment = text["comment"]
+	}
+	tags.Publisher = text["publisher"]
+	tags.BPM = text["bpm"]
+	tags.DeclaredAudioSize, _ = parseNumber(text["size"])
+	tags.Mood = text["mood"]
+	tags.Composer = text["composer"]
+	tags.Copyright = text["copyright"]
+	tags.EncodedBy = text["encodedby"]
+	tags.Encoder = text["encoder"]
+	tags.Language = text["language"]
+	tags.Writer = text["writer"]
+	tags.OriginalArtist = text["originalartist"]
+	tags.Media = text["media"]
+	tags.PodcastID = text["podcastid"]
+	tags.PodcastFeed = text["podcastfeed"]
+	tags.PodcastDescription = text["podcastdescription"]
+	tags.Keywords = text["keywords"]
+	tags.CopyrightYear, tags.CopyrightText = splitNoticeYear(text["copyright"])
+	tags.ProducedYear, tags.ProducedText = splitNoticeYear(text["producednotice"])
+	tags.Speed, _ = parseNumber(text["speed"])
+	tags.StartTime = text["starttime"]
+	tags.EndTime = text["endtime"]
+	if v, ok := text["originalrelease"]; ok {
+		tags.OriginalRelease, _ = parseID3v2Timestamp(v)
+	}
+}
+
+// ReadCompressed parses a gzip compressed stream, such as an archived MP3
+// or a tag stored separately, as Read would the decompressed stream. Only
+// the front ID3v2 tag is read since the decompressed stream can't seek.
+func ReadCompressed(r io.Reader) (*SimpleTags, error) {
+	buf := bufio.NewReader(r)
+	magic, err := buf.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return nil, fmt.Errorf("id3: not a gzip stream")
+	}
+	zr, err := gzip.NewReader(buf)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return Read(zr)
+}
+
+// ReadStreamTags parses the ID3v2 tag at the front of reader, which needn't
+// be seekable, e.g. an HTTP response body. Exactly the tag, including any
+// footer, is consumed so that the audio can be read from reader afterwards.
+// Returns ErrNoTags if reader doesn't start with an ID3v2 tag, in which case
+// up to 10 bytes have been consumed.
+func ReadStreamTags(reader io.Reader) (*SimpleTags, error) {
+	tags, text, err := readStream(reader)
+	if err != nil {
+		return nil, err
+	}
+	fillSimpleTags(tags, text)
+	return tags, nil
+}
+
+// ReadStream is like ReadStreamTags but returns the text frames like
+// ReadFile.
+//
+// Deprecated: Use ReadStreamTags, whose SimpleTags has a field for each of
+// these values alongside the structured frames.
+func ReadStream(reader io.Reader) (map[string]string, error) {
+	_, text, err := readStream(reader)
+	if err != nil {
+		return nil, err
+	}
+	return text, nil
+}
+
+// Parses the ID3v2 tag at the front of reader, see ReadStreamTags.
+func readStream(reader io.Reader) (*SimpleTags, map[string]string, error) {
+	data := make([]byte, 10)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, nil, ErrNoTags
+		}
+		return nil, nil, err
+	}
+	header, err := decodeID3v2Header(data)
+	if err != nil {
+		return nil, nil, ErrNoTags
+	}
+	size := int64(header.Size)
+	if header.Footer {
+		size += 10
+	}
+
+	// Whatever the parser leaves of the tag is skipped.
+	tag := io.LimitReader(reader, size)
+	tags, text, err := parseID3v2File(bufio.NewReader(io.MultiReader(bytes.NewReader(data), tag)), &Options{})
+	if _, skipErr := io.Copy(ioutil.Discard, tag); err == nil {
+		err = skipErr
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return tags, text, nil
+}
+
+// ReadTar parses the front ID3v2 tag of the current entry of tr, as
+// returned by its Next method. Tar entries can't seek so no other tags are
+// read. Returns ErrNoTags if the entry doesn't start with an ID3v2 tag.
+func ReadTar(tr *tar.Reader) (*SimpleTags, error) {
+	buf := bufio.NewReader(tr)
+	if !hasID3v2Tag(buf) {
+		return nil, ErrNoTags
+	}
+	return Read(buf)
+}
+
+// ReadAtOffset reads the tags of an MP3 stream embedded in r at offset,
+// such as one inside a container format. The ID3v2 tag is read from offset
+// but ID3v1, Lyrics3, APE and appended ID3v2 tags are looked for at the end
+// of r, as r has no notion of where the embedded stream ends. They are
+// therefore only found when the stream runs to the end of r; wrap r in an
+// io.SectionReader ending at the stream's end otherwise.
+func ReadAtOffset(r io.ReadSeeker, offset int64) (*SimpleTags, error) {
+	if _, err := r.Seek(offset, 0); err != nil {
+		return nil, err
+	}
+	return Read(r)
+}
+
+// Largest stream ReadSized will buffer in memory.
+const maxSizedRead = 64 << 20
+
+// ReadSized reads the tags of the size bytes of r, the trailing ones
+// included, for readers that know their size but can't seek. If r is an
+// io.ReaderAt it's read in place, otherwise the whole stream is buffered,
+// which fails for streams over 64 MiB.
+func ReadSized(r io.Reader, size int64) (*SimpleTags, error) {
+	if ra, ok := r.(io.ReaderAt); ok {
+		return Read(io.NewSectionReader(ra, 0, size))
+	}
+	if size < 0 || size > maxSizedRead {
+		return nil, fmt.Errorf("id3: can't buffer a %d byte stream, the limit is %d", size, maxSizedRead)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return Read(bytes.NewReader(data))
+}
+
+// ReadFileBuffered is like Read but reads r through a buffer of bufSize
+// bytes, which can save system calls on files with large pictures.
+func ReadFileBuffered(r io.ReadSeeker, bufSize int) (*SimpleTags, error) {
+	return ReadWithOptions(r, Options{BufferSize: bufSize})
+}
+
 // ReadFile parses seekable stream for ID3 information. Returns nil if
 // ID3 tag is not found or parsing fails.
+//
+// Deprecated: Use Read, whose SimpleTags has a field for each of these
+// values alongside the structured frames. ReadFile is kept for existing
+// callers.
 func ReadFile(reader io.ReadSeeker) (map[string]string, error) {
-	buf := bufio.NewReader(reader)
+	_, text, err := readTags(reader, &Options{})
+	if err != nil {
+		return nil, err
+	}
+	return text, nil
+}
 
-	// Initialize tags map so that we don't panic when assigning v1 tags
-	// if there are no v2 tags present.
-	tags := make(map[string]string)
+// Parses both tags, returning the structured frames alongside the merged
+// text frames keyed by their names in the ID3v2 tag maps.
+func readTags(reader io.Reader, opts *Options) (*SimpleTags, map[string]string, error) {
+	// SEEK offsets are relative to the tag, wherever the stream starts.
+	var tagStart int64
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		tagStart, _ = rs.Seek(0, 1)
+	}
 
-	tags, v2err := parseID3v2File(buf)
-	v1Tags, v1err := parseID3v1File(reader)
+	buf, skipped := fixID3v2Identifier(bufio.NewReaderSize(reader, opts.bufferSize()), opts.bufferSize(), opts.CaseInsensitiveFrames)
+	tagStart += skipped
+	if !looksLikeMP3(buf, reader) {
+		return nil, nil, ErrNotMP3
+	}
+
+	var tail *tailLayout
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		tail, _ = scanTail(rs)
+	}
+
+	// Without a tag up front there may still be one appended to the end.
+	if tail != nil && tail.id3v2 >= 0 && !hasID3v2Tag(buf) {
+		if _, err := reader.(io.ReadSeeker).Seek(tail.id3v2, 0); err == nil {
+			buf = bufio.NewReaderSize(reader, opts.bufferSize())
+			tagStart = tail.id3v2
+		}
+	}
+
+	tags, text, v2err := parseID3v2File(buf, opts)
+	if v2err == ErrUnsupportedVersion || v2err == ErrExperimental {
+		return nil, nil, v2err
+	}
+	if rs, ok := reader.(io.ReadSeeker); ok && v2err == nil {
+		readSupplementaryTags(rs, tagStart, tags, text, opts)
+	}
+	var v1Tags map[string]string
+	v1err := fmt.Errorf("stream is not seekable")
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		if opts.accepts(1) {
+			v1Tags, v1err = parseID3v1File(rs, opts)
+			if v1err == nil && tail != nil && tail.enhanced >= 0 {
+				if enhanced, err := parseID3v1EnhancedTag(rs, tail.enhanced); err == nil {
+					for k, v := range enhanced {
+						v1Tags[k] = v
+					}
+				}
+			}
+		} else if v2err != nil && hasID3v1Tag(rs) {
+			// only the rejected ID3v1 tag is present
+			return nil, nil, ErrUnsupportedVersion
+		} else {
+			v1err = fmt.Errorf("ID3v1 tags are not accepted")
+		}
+	}
 
 	if v1err != nil && v2err != nil {
-		return nil, fmt.Errorf("Error parsing ID3 tags: %v, %v", v1err, v2err)
+		return nil, nil, fmt.Errorf("Error parsing ID3 tags: %v, %v", v1err, v2err)
 	}
 
 	//If v2err returned an error tags will be nil
 	//At this point v1Tags is valid and we will set tag as an empty map so we can prevent system from panicking
 	if tags == nil {
-		tags = map[string]string{}
+		tags = &SimpleTags{}
+		text = map[string]string{}
+	}
+
+	// Lyrics3 fields extend the ID3v1 ones so they take priority over them.
+	if tail != nil && tail.lyrics3 >= 0 {
+		lyrics, l3Tags, err := parseLyrics3Tag(reader.(io.ReadSeeker), tail.lyrics3)
+		if err == nil {
+			if lyrics != nil {
+				tags.Lyrics = append(tags.Lyrics, *lyrics)
+			}
+			for k, v := range l3Tags {
+				if _, ok := text[k]; !ok {
+					text[k] = v
+				}
+			}
+		}
 	}
 
 	// Merge both results, prioritising id3v2
 	for k, v := range v1Tags {
-		if _, ok := tags[k]; !ok {
-			tags[k] = v
+		v2, ok := text[k]
+		if !ok {
+			text[k] = v
+		} else if !id3v1Matches(k, v, v2) {
+			if tags.conflicts == nil {
+				tags.conflicts = map[string][2]string{}
+			}
+			tags.conflicts[k] = [2]string{v, v2}
 		}
 	}
 
-	if len(tags) == 0 {
-		return nil, fmt.Errorf("No ID3 tags found on file")
+	// A header without any frames is not a tag worth reporting.
+	if len(text) == 0 && reflect.DeepEqual(tags, &SimpleTags{Header: tags.Header}) {
+		return tags, nil, ErrNoTags
 	}
 
-	return tags, nil
+	return tags, text, nil
+}
+
+// Follows the SEEK frames of the tag at start, parsing each supplementary
+// tag they point to as an update of tags and text. Tags that can't be read
+// end the chain.
+func readSupplementaryTags(rs io.ReadSeeker, start int64, tags *SimpleTags, text map[string]string, opts *Options) {
+	for t := tags; t.SeekOffset > 0; {
+		start += 10 + int64(t.Header.Size) + t.SeekOffset
+		if t.Header.Footer {
+			start += 10
+		}
+		if _, err := rs.Seek(start, 0); err != nil {
+			return
+		}
+		var next map[string]string
+		var err error
+		if t, next, err = parseID3v2File(bufio.NewReaderSize(rs, opts.bufferSize()), opts); err != nil {
+			return
+		}
+		for k, v := range next {
+			text[k] = v
+		}
+		tags.Comments = append(tags.Comments, t.Comments...)
+	}
+}
+
+// Quick format sniff: reports whether buf starts with an ID3v2 header or an
+// MPEG frame sync, or reader (when seekable) ends with an ID3v1 tag.
+func looksLikeMP3(buf *bufio.Reader, reader io.Reader) bool {
+	if hasID3v2Tag(buf) || hasMPEGSync(buf) {
+		return true
+	}
+	rs, ok := reader.(io.ReadSeeker)
+	return ok && hasID3v1Tag(rs)
 }
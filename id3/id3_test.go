@@ -15,9 +15,17 @@
 package id3
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
 	"os"
 	"path"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -105,22 +113,453 @@ func TestEmpty(t *testing.T) {
 	}
 }
 
+func TestNoFrames(t *testing.T) {
+	data := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 0}
+
+	f, err := Read(bytes.NewReader(data))
+	if err != ErrNoTags {
+		t.Errorf("Read: expected ErrNoTags got %v", err)
+	}
+	if f == nil || f.Header == nil || f.Header.Version != 4 {
+		t.Errorf("Read: expected empty tags with a v2.4 header got %v", f)
+	}
+
+	tags, err := ReadFile(bytes.NewReader(data))
+	if err != ErrNoTags {
+		t.Errorf("ReadFile: expected ErrNoTags got %v", err)
+	}
+	if tags != nil {
+		t.Errorf("ReadFile: expected nil tags got %v", tags)
+	}
+}
+
+func TestNotMP3(t *testing.T) {
+	inputs := map[string][]byte{
+		"FLAC": []byte("fLaC\x00\x00\x00\x22"),
+		"JPEG": []byte("\xff\xd8\xff\xe0\x00\x10JFIF"),
+	}
+	for name, data := range inputs {
+		if _, err := Read(bytes.NewReader(data)); err != ErrNotMP3 {
+			t.Errorf("%s: expected ErrNotMP3 got %v", name, err)
+		}
+	}
+
+	v1 := append(make([]byte, 64), "TAG"...)
+	v1 = append(v1, make([]byte, 125)...)
+	inputs = map[string][]byte{
+		"MPEG":  []byte("\xff\xfb\x90\x00"),
+		"ID3v1": v1,
+	}
+	for name, data := range inputs {
+		if _, err := Read(bytes.NewReader(data)); err == ErrNotMP3 {
+			t.Errorf("%s: unexpected ErrNotMP3", name)
+		}
+	}
+}
+
+func TestAcceptVersions(t *testing.T) {
+	v1 := buildID3v1Tag("V1 Title")
+	copy(v1[63:93], "V1 Album")
+	v23 := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")))
+	v24 := append(buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Title"))), v1...)
+	opts := Options{AcceptVersions: []int{4}}
+
+	f, err := ReadWithOptions(bytes.NewReader(v24), opts)
+	if err != nil {
+		t.Fatalf("v2.4: %s", err)
+	}
+	if f.Title != "Title" || f.Album != "" {
+		t.Errorf("v2.4: expected 'Title' and no ID3v1 album got '%s' and '%s'", f.Title, f.Album)
+	}
+
+	if _, err := ReadWithOptions(bytes.NewReader(v23), opts); err != ErrUnsupportedVersion {
+		t.Errorf("v2.3: expected ErrUnsupportedVersion got %v", err)
+	}
+	if _, err := ReadWithOptions(bytes.NewReader(v1), opts); err != ErrUnsupportedVersion {
+		t.Errorf("v1: expected ErrUnsupportedVersion got %v", err)
+	}
+
+	f, err = ReadWithOptions(bytes.NewReader(v24), Options{AcceptVersions: []int{1, 4}})
+	if err != nil {
+		t.Fatalf("v2.4 and v1: %s", err)
+	}
+	if f.Album != "V1 Album" {
+		t.Errorf("v2.4 and v1: expected 'V1 Album' got '%s'", f.Album)
+	}
+}
+
+func TestReadCompressed(t *testing.T) {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title"))))
+	zw.Close()
+
+	f, err := ReadCompressed(&b)
+	if err != nil {
+		t.Fatalf("ReadCompressed: %s", err)
+	}
+	if f.Title != "Title" {
+		t.Errorf("Title: expected 'Title' got '%s'", f.Title)
+	}
+
+	_, err = ReadCompressed(bytes.NewReader(buildID3v2Tag(3)))
+	if err == nil || !strings.Contains(err.Error(), "not a gzip stream") {
+		t.Errorf("expected a gzip error got %v", err)
+	}
+}
+
+func TestSameShapeAcrossVersions(t *testing.T) {
+	v22 := buildID3v2Tag(2,
+		buildID3v2Frame(2, "TT2", []byte("\x00Title")),
+		buildID3v2Frame(2, "TP1", []byte("\x00Artist")),
+		buildID3v2Frame(2, "TAL", []byte("\x00Album")),
+		buildID3v2Frame(2, "TRK", []byte("\x003/12")),
+		buildID3v2Frame(2, "TCO", []byte("\x00(17)")),
+		buildID3v2Frame(2, "TCM", []byte("\x00Composer")),
+		buildID3v2Frame(2, "COM", []byte("\x00eng\x00Comment")))
+	v24 := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Artist")),
+		buildID3v2Frame(4, "TALB", []byte("\x03Album")),
+		buildID3v2Frame(4, "TRCK", []byte("\x033/12")),
+		buildID3v2Frame(4, "TCON", []byte("\x03(17)")),
+		buildID3v2Frame(4, "TCOM", []byte("\x03Composer")),
+		buildID3v2Frame(4, "COMM", []byte("\x03eng\x00Comment")))
+
+	f22, err := Read(bytes.NewReader(v22))
+	if err != nil {
+		t.Fatalf("v2.2: Read: %s", err)
+	}
+	f24, err := Read(bytes.NewReader(v24))
+	if err != nil {
+		t.Fatalf("v2.4: Read: %s", err)
+	}
+	if f22.Title != "Title" || f22.Genre != "Rock" || f22.Composer != "Composer" {
+		t.Errorf("v2.2: got %+v", f22)
+	}
+	// only the header and TextFrames, keyed by the IDs of each version,
+	// tell them apart
+	if f22.TextFrames["TT2"] != "Title" || f24.TextFrames["TIT2"] != "Title" {
+		t.Errorf("expected TT2 and TIT2 got %q and %q", f22.TextFrames, f24.TextFrames)
+	}
+	f22.TextFrames, f24.TextFrames = nil, nil
+	expected := map[string][2]string{"Header.Version": {"2", "4"}, "Header.Size": {fmt.Sprint(len(v22) - 10), fmt.Sprint(len(v24) - 10)}}
+	if d := Diff(f22, f24); !reflect.DeepEqual(d, expected) {
+		t.Errorf("expected %q got %q", expected, d)
+	}
+
+	// the deprecated map shares the values
+	text, err := ReadFile(bytes.NewReader(v22))
+	if err != nil || text["title"] != f22.Title || text["composer"] != f22.Composer {
+		t.Errorf("ReadFile: got %q, %v", text, err)
+	}
+}
+
+func TestReadStream(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	frames := [][]byte{
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Artist")),
+	}
+	padded := buildID3v2Tag(4, append(frames, make([]byte, 100))...)
+	footered := addID3v2Footer(buildID3v2Tag(4, frames...))
+
+	for _, tag := range [][]byte{padded, footered} {
+		// hide Seek, as from an HTTP response body
+		r := struct{ io.Reader }{bytes.NewReader(append(append([]byte{}, tag...), audio...))}
+		text, err := ReadStream(r)
+		if err != nil {
+			t.Fatalf("ReadStream: %s", err)
+		}
+		if text["title"] != "Title" || text["artist"] != "Artist" {
+			t.Errorf("expected 'Title', 'Artist' got %q", text)
+		}
+		rest, err := ioutil.ReadAll(r)
+		if err != nil || !bytes.Equal(rest, audio) {
+			t.Errorf("expected the audio to follow the tag got %d bytes, %v", len(rest), err)
+		}
+
+		r = struct{ io.Reader }{bytes.NewReader(append(append([]byte{}, tag...), audio...))}
+		f, err := ReadStreamTags(r)
+		if err != nil {
+			t.Fatalf("ReadStreamTags: %s", err)
+		}
+		if f.Title != "Title" || f.Artist != "Artist" || f.Header.Version != 4 {
+			t.Errorf("expected a v2.4 tag with 'Title', 'Artist' got %+v", f)
+		}
+		if rest, err = ioutil.ReadAll(r); err != nil || !bytes.Equal(rest, audio) {
+			t.Errorf("ReadStreamTags: expected the audio to follow the tag got %d bytes, %v", len(rest), err)
+		}
+	}
+
+	if _, err := ReadStream(bytes.NewReader(audio)); err != ErrNoTags {
+		t.Errorf("expected ErrNoTags got %v", err)
+	}
+	if _, err := ReadStreamTags(bytes.NewReader(audio)); err != ErrNoTags {
+		t.Errorf("ReadStreamTags: expected ErrNoTags got %v", err)
+	}
+}
+
+func TestReadTar(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	mp3 := append(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title"))), audio...)
+
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for name, data := range map[string][]byte{"song.mp3": mp3, "untagged.mp3": audio} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+		tw.Write(data)
+	}
+	tw.Close()
+
+	tr := tar.NewReader(&b)
+	entries := 0
+	for ; ; entries++ {
+		h, err := tr.Next()
+		if err != nil {
+			break
+		}
+		f, err := ReadTar(tr)
+		switch h.Name {
+		case "song.mp3":
+			if err != nil || f.Title != "Title" {
+				t.Errorf("%s: expected 'Title' got %v (%v)", h.Name, f, err)
+			}
+		case "untagged.mp3":
+			if err != ErrNoTags {
+				t.Errorf("%s: expected ErrNoTags got %v", h.Name, err)
+			}
+		}
+	}
+	if entries != 2 {
+		t.Errorf("expected 2 entries got %d", entries)
+	}
+}
+
+func TestReadAtOffset(t *testing.T) {
+	container := []byte("CONTAINER HEADER")
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	first := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "SEEK", []byte{0, 0, 1, 0}))
+	update := buildID3v2Tag(4, buildID3v2Frame(4, "TALB", []byte("\x03Album")))
+	stream := append(append(append(first, audio...), update...), audio...)
+	file := append(append(append([]byte{}, container...), stream...), buildID3v1Tag("V1 Title")...)
+
+	f, err := ReadAtOffset(bytes.NewReader(file), int64(len(container)))
+	if err != nil {
+		t.Fatalf("ReadAtOffset: %s", err)
+	}
+	// the SEEK frame is followed from the embedded tag, and the ID3v1 tag
+	// at the end of the file is merged in
+	if f.Title != "Title" || f.Album != "Album" {
+		t.Errorf("expected 'Title', 'Album' got %q, %q", f.Title, f.Album)
+	}
+	if c := f.Conflicts()["title"]; c[0] != "V1 Title" {
+		t.Errorf("expected the ID3v1 title to be read got %q", c)
+	}
+}
+
+func TestMultipartFile(t *testing.T) {
+	data, err := ioutil.ReadFile(path.Join("..", "test", "test_230.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// test_230.mp3 has no ID3v1 tag, so append one whose artist disagrees
+	// with the ID3v2 tag and whose comment only ReadFile exposes
+	v1 := buildID3v1Tag("Everything In Its Right Place")
+	copy(v1[33:63], "Thom Yorke")
+	copy(v1[97:127], "From the ID3v1 tag")
+	data = append(data, v1...)
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", "test_230.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	w.Close()
+
+	// A large maxMemory keeps the file in memory, a tiny one spills it to a
+	// temporary file on disk.
+	for _, maxMemory := range []int64{int64(len(data)) * 2, 1} {
+		r := multipart.NewReader(bytes.NewReader(body.Bytes()), w.Boundary())
+		form, err := r.ReadForm(maxMemory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer form.RemoveAll()
+
+		f, err := form.File["file"][0].Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		tags, err := Read(f)
+		if err != nil {
+			t.Errorf("maxMemory %d: %s", maxMemory, err)
+			continue
+		}
+		if tags.Title != "Everything In Its Right Place" || tags.Genre != "Alternative" {
+			t.Errorf("maxMemory %d: got '%s'/'%s'", maxMemory, tags.Title, tags.Genre)
+		}
+		expected := [2]string{"Thom Yorke", "Radiohead"}
+		if c := tags.Conflicts()["artist"]; c != expected {
+			t.Errorf("maxMemory %d: expected the ID3v1 artist to conflict got %q", maxMemory, c)
+		}
+
+		if _, err := f.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		text, err := ReadFile(f)
+		if err != nil {
+			t.Errorf("maxMemory %d: ReadFile: %s", maxMemory, err)
+			continue
+		}
+		// the ID3v2 comment is keyed "comments"
+		if text["title"] != "Everything In Its Right Place" || text["comment"] != "From the ID3v1 tag" {
+			t.Errorf("maxMemory %d: ReadFile: got %q", maxMemory, text)
+		}
+	}
+}
+
 func TestID3v220(t *testing.T) {
-	testFile(t, fileTest{"test_220.mp3", SimpleTags{&ID3v2Header{2, 0, false, false, false, false, 226741},
-		"There There", "Radiohead", "Hail To The Thief", "2003", "9", "", "Alternative", ""}})
+	testFile(t, fileTest{"test_220.mp3", SimpleTags{
+		Header: &ID3v2Header{2, 0, false, false, false, false, 226741},
+		Title:  "There There",
+		Artist: "Radiohead",
+		Album:  "Hail To The Thief",
+		Year:   "2003",
+		Track:  "9",
+		Genre:  "Alternative",
+	}})
 }
 
 func TestID3v230(t *testing.T) {
-	testFile(t, fileTest{"test_230.mp3", SimpleTags{&ID3v2Header{3, 0, false, false, false, false, 150717},
-		"Everything In Its Right Place", "Radiohead", "Kid A", "2000", "1", "", "Alternative", ""}})
+	testFile(t, fileTest{"test_230.mp3", SimpleTags{
+		Header: &ID3v2Header{3, 0, false, false, false, false, 150717},
+		Title:  "Everything In Its Right Place",
+		Artist: "Radiohead",
+		Album:  "Kid A",
+		Year:   "2000",
+		Track:  "1",
+		Genre:  "Alternative",
+	}})
 }
 
 func TestID3v240(t *testing.T) {
-	testFile(t, fileTest{"test_240.mp3", SimpleTags{&ID3v2Header{4, 0, false, false, false, false, 165126},
-		"Give Up The Ghost", "Radiohead", "The King Of Limbs", "2011", "07/08", "1/1", "Alternative", ""}})
+	testFile(t, fileTest{"test_240.mp3", SimpleTags{
+		Header: &ID3v2Header{4, 0, true, false, false, false, 165126},
+		Title:  "Give Up The Ghost",
+		Artist: "Radiohead",
+		Album:  "The King Of Limbs",
+		Year:   "2011",
+		Track:  "07/08",
+		Disc:   "1/1",
+		Genre:  "Alternative",
+	}})
 }
 
 func TestISO8859_1(t *testing.T) {
-	testFile(t, fileTest{"test_iso8859_1.mp3", SimpleTags{&ID3v2Header{3, 0, false, false, false, false, 273649},
-		"Pompeii Am Götterdämmerung", "The Flaming Lips", "At War With The Mystics", "2006", "11", "1/1", "Unknown", ""}})
+	testFile(t, fileTest{"test_iso8859_1.mp3", SimpleTags{
+		Header: &ID3v2Header{3, 0, false, false, false, false, 273649},
+		Title:  "Pompeii Am Götterdämmerung",
+		Artist: "The Flaming Lips",
+		Album:  "At War With The Mystics",
+		Year:   "2006",
+		Track:  "11",
+		Disc:   "1/1",
+		Genre:  "Psychedelic Rock",
+	}})
+}
+
+// Assembles an ID3v2 tag of the given major version around raw frames.
+func buildID3v2Tag(version int, frames ...[]byte) []byte {
+	var body []byte
+	for _, f := range frames {
+		body = append(body, f...)
+	}
+	tag := []byte{'I', 'D', '3', byte(version), 0, 0}
+	tag = append(tag, syncSafe(len(body))...)
+	return append(tag, body...)
+}
+
+// Assembles a single frame using the version specific size and flags layout.
+func buildID3v2Frame(version int, id string, data []byte) []byte {
+	f := []byte(id)
+	n := len(data)
+	switch version {
+	case 2:
+		f = append(f, byte(n>>16), byte(n>>8), byte(n))
+	case 3:
+		f = append(f, byte(n>>24), byte(n>>16), byte(n>>8), byte(n), 0, 0)
+	case 4:
+		f = append(f, syncSafe(n)...)
+		f = append(f, 0, 0)
+	}
+	return append(f, data...)
+}
+
+func syncSafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+}
+
+// Counts the reads made of an io.ReadSeeker.
+type countingReader struct {
+	io.ReadSeeker
+	reads int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	c.reads++
+	return c.ReadSeeker.Read(p)
+}
+
+func TestReadFileBuffered(t *testing.T) {
+	art := make([]byte, 256*1024)
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "APIC", append([]byte("\x00image/jpeg\x00\x03\x00"), art...)))
+
+	small := &countingReader{ReadSeeker: bytes.NewReader(tag)}
+	if _, err := ReadWithOptions(small, Options{}); err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	large := &countingReader{ReadSeeker: bytes.NewReader(tag)}
+	f, err := ReadFileBuffered(large, 1024*1024)
+	if err != nil {
+		t.Fatalf("ReadFileBuffered: %s", err)
+	}
+	if f.Title != "Title" || len(f.Pictures) != 1 || len(f.Pictures[0].Data) != len(art) {
+		t.Errorf("expected the title and picture to be read")
+	}
+	if large.reads >= small.reads {
+		t.Errorf("expected fewer than %d reads got %d", small.reads, large.reads)
+	}
+}
+
+func TestReadSized(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	file := append(append([]byte{}, audio...), buildID3v1Tag("V1 Title")...)
+
+	// a plain reader is buffered, a ReaderAt is read in place
+	for _, r := range []io.Reader{struct{ io.Reader }{bytes.NewReader(file)}, bytes.NewReader(file)} {
+		f, err := ReadSized(r, int64(len(file)))
+		if err != nil {
+			t.Fatalf("%T: ReadSized: %s", r, err)
+		}
+		if f.Title != "V1 Title" {
+			t.Errorf("%T: expected 'V1 Title' got '%s'", r, f.Title)
+		}
+	}
+
+	if _, err := ReadSized(struct{ io.Reader }{bytes.NewReader(file)}, 1<<40); err == nil {
+		t.Error("expected an error for a 1 TiB stream")
+	}
+	if _, err := ReadSized(struct{ io.Reader }{bytes.NewReader(file)}, int64(len(file))+1); err == nil {
+		t.Error("expected an error for a stream shorter than its size")
+	}
 }
@@ -0,0 +1,71 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package id3test provides helpers for testing code that builds tags for
+// the id3 package's writer.
+package id3test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bobertlo/go-id3/id3"
+)
+
+// RoundTrip writes tags as an ID3v2.4 tag, reads it back and reports an
+// error on t for each field, comment or picture that didn't survive. The
+// tags read back are returned for further checks.
+func RoundTrip(t testing.TB, tags *id3.SimpleTags) *id3.SimpleTags {
+	t.Helper()
+
+	var b bytes.Buffer
+	if err := id3.WriteTag(&b, tags, id3.WriteOptions{}); err != nil {
+		t.Fatalf("WriteTag: %s", err)
+	}
+	got, err := id3.Read(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	// Artists is written in place of Artist.
+	expected := tags.Map()
+	if len(tags.Artists) > 0 {
+		expected["artist"] = strings.Join(tags.Artists, "/")
+	}
+	actual := got.Map()
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("%s: wrote %q read %q", k, v, actual[k])
+		}
+	}
+	for k, v := range actual {
+		if _, ok := expected[k]; !ok {
+			t.Errorf("%s: wrote nothing read %q", k, v)
+		}
+	}
+
+	if len(tags.Comments) > 0 || len(got.Comments) > 0 {
+		if !reflect.DeepEqual(got.Comments, tags.Comments) {
+			t.Errorf("Comments: wrote %+v read %+v", tags.Comments, got.Comments)
+		}
+	}
+	if len(tags.Pictures) > 0 || len(got.Pictures) > 0 {
+		if !reflect.DeepEqual(got.Pictures, tags.Pictures) {
+			t.Errorf("Pictures: wrote %d read %d", len(tags.Pictures), len(got.Pictures))
+		}
+	}
+	return got
+}
@@ -0,0 +1,65 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bobertlo/go-id3/id3"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tags := &id3.SimpleTags{
+		Title:           "Get Lucky",
+		Artists:         []string{"Daft Punk", "Pharrell Williams"},
+		Album:           "Random Access Memories",
+		Track:           "8/13",
+		Genre:           "Dance",
+		BPM:             "116",
+		OriginalRelease: time.Date(2013, time.April, 19, 0, 0, 0, 0, time.UTC),
+		Comments:        []id3.Comment{{Language: "eng", Text: "A comment"}},
+		Pictures: []id3.Picture{{
+			MIMEType:    "image/png",
+			PictureType: id3.PictureTypeFrontCover,
+			Data:        []byte("\x89PNG"),
+		}},
+	}
+	if got := RoundTrip(t, tags); got.Header.Version != 4 {
+		t.Errorf("expected an ID3v2.4 tag got v2.%d", got.Header.Version)
+	}
+}
+
+// Records the failures RoundTrip reports.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRoundTripReportsLoss(t *testing.T) {
+	// ID3v1 genre codes are written as is but read back by name.
+	r := &recorder{TB: t}
+	RoundTrip(r, &id3.SimpleTags{Title: "Title", Genre: "(17)"})
+	if len(r.errors) != 1 {
+		t.Errorf("expected one error for the genre got %q", r.errors)
+	}
+}
@@ -38,6 +38,8 @@ func hasID3v1Tag(reader io.ReadSeeker) bool {
 	if err != nil {
 		return false
 	}
+	defer reader.Seek(origin, 0)
+
 	_, err = reader.Seek(-128, 2)
 	if err != nil {
 		return false
@@ -47,7 +49,6 @@ func hasID3v1Tag(reader io.ReadSeeker) bool {
 	if err != nil || num != 3 {
 		return false
 	}
-	reader.Seek(origin, 0)
 	return string(buf) == "TAG"
 }
 
@@ -59,7 +60,7 @@ func readID3v1String(reader io.Reader, c int) (string, error) {
 	return strings.TrimRight(string(data), "\u0000"), nil
 }
 
-func parseID3v1File(reader io.ReadSeeker) (map[string]string, error) {
+func parseID3v1File(reader io.ReadSeeker, opts *Options) (map[string]string, error) {
 	origin, err := reader.Seek(-128, 2)
 	if err != nil {
 		return nil, fmt.Errorf("seek failed")
@@ -100,12 +101,63 @@ func parseID3v1File(reader io.ReadSeeker) (map[string]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("read error")
 	}
-	if int(data[0]) >= len(id3v1Genres) {
+	// 255 means no genre while other codes past the table are unknown
+	genres := opts.genres()
+	if data[0] == 255 {
+		tags["genre"] = ""
+	} else if int(data[0]) >= len(genres) {
 		tags["genre"] = "Unspecified"
 	} else {
-		tags["genre"] = id3v1Genres[int(data[0])]
+		tags["genre"] = genres[int(data[0])]
 	}
 
 	reader.Seek(origin, 0)
 	return tags, nil
 }
+
+// Length of the "TAG+" block some taggers write before the ID3v1 tag.
+const id3v1EnhancedLength = 227
+
+// Checks for an enhanced ID3v1 block at offset. The reader's position is
+// restored before returning.
+func hasID3v1EnhancedTag(reader io.ReadSeeker, offset int64) bool {
+	origin, err := reader.Seek(0, 1)
+	if err != nil {
+		return false
+	}
+	defer reader.Seek(origin, 0)
+
+	if _, err := reader.Seek(offset, 0); err != nil {
+		return false
+	}
+	data, err := readBytes(reader, 4)
+	return err == nil && string(data) == "TAG+"
+}
+
+// Parses the speed and the start and end times of the enhanced ID3v1 block
+// at offset: "TAG+", 60 more bytes each of title, artist and album, the
+// speed from 1 (slow) to 4 (hardcore), a 30 byte genre and the "mmm:ss"
+// start and end times. Unset fields are left out.
+func parseID3v1EnhancedTag(reader io.ReadSeeker, offset int64) (map[string]string, error) {
+	if _, err := reader.Seek(offset, 0); err != nil {
+		return nil, err
+	}
+	data, err := readBytes(reader, id3v1EnhancedLength)
+	if err != nil {
+		return nil, err
+	}
+	if string(data[:4]) != "TAG+" {
+		return nil, fmt.Errorf("could not parse enhanced ID3v1 tag")
+	}
+
+	tags := map[string]string{}
+	if speed := data[184]; speed > 0 && speed <= 4 {
+		tags["speed"] = fmt.Sprint(speed)
+	}
+	for k, v := range map[string][]byte{"starttime": data[215:221], "endtime": data[221:227]} {
+		if s := strings.TrimSpace(strings.TrimRight(string(v), "\u0000")); s != "" {
+			tags[k] = s
+		}
+	}
+	return tags, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Assembles a 128 byte ID3v1 tag with the given title.
+func buildID3v1Tag(title string) []byte {
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	copy(tag[3:33], title)
+	tag[127] = 255
+	return tag
+}
+
+func TestID3v1StrictGenres(t *testing.T) {
+	tests := []struct {
+		code   byte
+		strict bool
+		genre  string
+	}{
+		{79, true, "Hard Rock"},
+		{80, true, "Unspecified"},
+		{80, false, "Folk"},
+		{191, false, "Psybient"},
+		{192, false, "Unspecified"},
+	}
+	for _, test := range tests {
+		tag := buildID3v1Tag("Title")
+		tag[127] = test.code
+		f, err := ReadWithOptions(bytes.NewReader(tag), Options{StrictGenres: test.strict})
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Genre != test.genre {
+			t.Errorf("%d strict=%t: expected '%s' got '%s'", test.code, test.strict, test.genre, f.Genre)
+		}
+	}
+}
+
+func TestID3v1GenreBounds(t *testing.T) {
+	tests := map[byte]string{
+		0:                          "Blues",
+		byte(len(id3v1Genres) - 1): id3v1Genres[len(id3v1Genres)-1],
+		byte(len(id3v1Genres)):     "Unspecified",
+		254:                        "Unspecified",
+		255:                        "",
+	}
+	for code, genre := range tests {
+		tag := buildID3v1Tag("Title")
+		tag[127] = code
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%d: Read: %s", code, err)
+		}
+		if f.Genre != genre {
+			t.Errorf("%d: expected '%s' got '%s'", code, genre, f.Genre)
+		}
+	}
+}
+
+func TestGenreTable(t *testing.T) {
+	table := append([]string{}, id3v1Genres...)
+	table[17] = "Rock (de)"
+	opts := Options{GenreTable: table[:20]}
+
+	v1 := buildID3v1Tag("Title")
+	v1[127] = 17
+	v2 := buildID3v2Tag(3, buildID3v2Frame(3, "TCON", []byte("\x00(17)")))
+	for _, tag := range [][]byte{v1, v2} {
+		f, err := ReadWithOptions(bytes.NewReader(tag), opts)
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Genre != "Rock (de)" {
+			t.Errorf("expected 'Rock (de)' got '%s'", f.Genre)
+		}
+	}
+
+	// codes past the end of the table are out of range
+	v1[127] = 79
+	f, err := ReadWithOptions(bytes.NewReader(v1), opts)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Genre != "Unspecified" {
+		t.Errorf("expected 'Unspecified' got '%s'", f.Genre)
+	}
+}
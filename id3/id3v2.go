@@ -16,8 +16,12 @@ package id3
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"strings"
 )
 
 // A parsed ID3v2 header as defined in Section 3 of
@@ -32,66 +36,659 @@ type ID3v2Header struct {
 	Size              int32
 }
 
-func parseID3v2File(reader *bufio.Reader) (map[string]string, error) {
+// ExtendedHeader holds the optional fields of an ID3v2.3 or ID3v2.4
+// extended header.
+//
+// Refer to section 3.2 of http://id3.org/id3v2.4.0-structure
+type ExtendedHeader struct {
+	// Update marks an ID3v2.4 tag as an update of an earlier tag.
+	Update bool
+
+	// CRC is the CRC-32 of the frame data when HasCRC is set. It isn't
+	// verified.
+	HasCRC bool
+	CRC    uint32
+
+	// Restrictions is the ID3v2.4 tag restrictions byte, limiting e.g. the
+	// tag size and text encodings, when HasRestrictions is set.
+	HasRestrictions bool
+	Restrictions    byte
+}
+
+// HasID3v2 checks whether header, the first 10 bytes of a stream, is an
+// ID3v2 header of a supported version. It returns the major version and the
+// tag size excluding the 10 byte header and any footer, so that exactly
+// 10+size bytes hold the header and frames.
+func HasID3v2(header []byte) (version int, size int, ok bool) {
+	h, err := decodeID3v2Header(header)
+	if err != nil || h.Version < 2 || h.Version > 4 {
+		return 0, 0, false
+	}
+	return h.Version, int(h.Size), true
+}
+
+// TagSize returns the length in bytes of the ID3v2 tag at the front of r,
+// including its header and any footer, or 0 if there is none. Only the
+// 10 byte header is read so r needn't be seekable.
+func TagSize(r io.Reader) (int64, error) {
+	data := make([]byte, 10)
+	if _, err := io.ReadFull(r, data); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return 0, nil
+		}
+		return 0, err
+	}
+	h, err := decodeID3v2Header(data)
+	if err != nil {
+		return 0, nil
+	}
+	size := 10 + int64(h.Size)
+	if h.Footer {
+		size += 10
+	}
+	return size, nil
+}
+
+// ReadFrame reads a single frame of the given ID3v2 major version from r,
+// returning its ID, the raw frame flags and the undecoded frame body. ID3v2.2
+// frames carry no flags so they are always returned as zero.
+func ReadFrame(r *bufio.Reader, version int) (id string, flags [2]byte, data []byte, err error) {
 	var parseSize func(*bufio.Reader) (int, error)
+	idLen := 4
+	switch version {
+	case 2:
+		parseSize = parseID3v22FrameSize
+		idLen = 3
+	case 3:
+		parseSize = parseID3v23FrameSize
+	case 4:
+		parseSize = parseID3v24FrameSize
+	default:
+		return "", flags, nil, fmt.Errorf("Unrecognized ID3v2 version: %d", version)
+	}
+
+	b, err := readBytes(r, idLen)
+	if err != nil {
+		return "", flags, nil, err
+	}
+	size, err := parseSize(r)
+	if err != nil {
+		return "", flags, nil, err
+	}
+	if size < 0 {
+		return "", flags, nil, fmt.Errorf("invalid frame size: %d", size)
+	}
+	// frame flags are only present in v2.3 and v2.4
+	if version == 3 || version == 4 {
+		f, err := readBytes(r, 2)
+		if err != nil {
+			return "", flags, nil, err
+		}
+		copy(flags[:], f)
+	}
+	// The declared size can't be trusted, so the body is copied into a
+	// buffer that only grows as data arrives rather than allocated up front.
+	var buf bytes.Buffer
+	if n, err := io.CopyN(&buf, r, int64(size)); err != nil {
+		if err == io.EOF {
+			return "", flags, nil, fmt.Errorf("frame %s: short read, %d/%d", b, n, size)
+		}
+		return "", flags, nil, err
+	}
+	return string(b), flags, buf.Bytes(), nil
+}
+
+// Parses the ID3v2 tag at the front of reader. Structured frames such as
+// comments are stored in the returned SimpleTags while plain text frames are
+// returned in a map keyed by their names in the version specific tag map.
+func parseID3v2File(reader *bufio.Reader, opts *Options) (*SimpleTags, map[string]string, error) {
 	var tagMap map[string]string
-	var tagLen int
 
-	// parse header and setup version specific functions/data
+	// parse header and setup version specific data
 	header, err := parseID3v2Header(reader)
 	if err != nil {
-		return nil, fmt.Errorf("parseHeader: %s", err)
+		return nil, nil, fmt.Errorf("parseHeader: %s", err)
+	}
+	if !opts.accepts(header.Version) {
+		return nil, nil, ErrUnsupportedVersion
+	}
+	if header.Experimental && opts.RejectExperimental {
+		return nil, nil, ErrExperimental
 	}
 	switch header.Version {
 	case 2:
-		parseSize = parseID3v22FrameSize
 		tagMap = ID3v22Tags
-		tagLen = 3
 	case 3:
-		parseSize = parseID3v23FrameSize
 		tagMap = ID3v23Tags
-		tagLen = 4
 	case 4:
-		parseSize = parseID3v24FrameSize
 		tagMap = ID3v24Tags
-		tagLen = 4
 	default:
-		return nil, fmt.Errorf("Unrecognized ID3v2 version: %d", header.Version)
+		return nil, nil, fmt.Errorf("Unrecognized ID3v2 version: %d", header.Version)
 	}
 
+	t := &SimpleTags{Header: header}
+	if header.Experimental {
+		t.Warnings = append(t.Warnings, "tag marked experimental")
+	}
 	tags := map[string]string{}
-	lreader := bufio.NewReader(io.LimitReader(reader, int64(header.Size)))
-	for hasID3v2Frame(lreader, tagLen) {
-		b, err := readBytes(lreader, tagLen)
+	// Frames that can't be decoded are skipped with a warning, except in
+	// strict mode.
+	skip := func(f *Frame, offset int, err error) error {
+		if opts.strict {
+			return &Violation{Offset: offset, FrameID: f.ID, Rule: err.Error()}
+		}
+		t.Warnings = append(t.Warnings, fmt.Sprintf("frame %s at offset %d skipped: %s", f.ID, offset, err))
+		return nil
+	}
+	end, ext, err := walkID3v2Frames(reader, header, opts, func(f *Frame, offset int) error {
+		if err := keepID3v2TextFrame(t, f, opts); err != nil {
+			return skip(f, offset, err)
+		}
+		if err := keepID3v2URLFrame(t, f); err != nil {
+			return skip(f, offset, err)
+		}
+		if id, ok := tagMap[f.ID]; ok {
+			if err := parseID3v2Frame(t, tags, id, f, opts); err != nil {
+				return skip(f, offset, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	t.ExtendedHeader = ext
+	if end > int(header.Size) {
+		t.Warnings = append(t.Warnings, fmt.Sprintf("tag size %d is smaller than its %d bytes of frames", header.Size, end))
+	}
+	if header.Footer && header.Version == 4 {
+		if err := checkID3v2Footer(reader, header); err != nil {
+			t.Warnings = append(t.Warnings, err.Error())
+		}
+	}
+	return t, tags, nil
+}
+
+// Reads the footer of the ID3v2.4 tag whose header and frames have just been
+// read from reader. The footer repeats the header with the "ID3" identifier
+// reversed, so any difference means one of them is corrupt.
+//
+// Refer to section 3.4 of http://id3.org/id3v2.4.0-structure
+func checkID3v2Footer(reader io.Reader, header *ID3v2Header) error {
+	data, err := readBytes(reader, 10)
+	if err != nil || string(data[:3]) != "3DI" {
+		return fmt.Errorf("footer flag set but no footer follows the tag")
+	}
+	footer, _ := decodeID3v2Header(append([]byte("ID3"), data[3:]...))
+	if footer.Size != header.Size {
+		return fmt.Errorf("footer size %d disagrees with the tag size %d", footer.Size, header.Size)
+	}
+	if *footer != *header {
+		return fmt.Errorf("footer version or flags disagree with the header")
+	}
+	return nil
+}
+
+// Stores the value of f in t.TextFrames if it's a text frame, i.e. a frame
+// other than TXXX whose ID starts with "T" and whose data starts with a
+// valid encoding byte. Values that can't be decoded are left out and the
+// error returned.
+func keepID3v2TextFrame(t *SimpleTags, f *Frame, opts *Options) error {
+	if f.ID[0] != 'T' || f.ID == "TXXX" || f.ID == "TXX" || len(f.Data) == 0 || f.Data[0] > 3 {
+		return nil
+	}
+	data := f.Data
+	if opts.LenientEncoding {
+		data = fixUTF8BOM(data)
+	}
+	values, err := parseID3v2Strings(data)
+	if err != nil {
+		return err
+	}
+	if t.TextFrames == nil {
+		t.TextFrames = map[string]string{}
+	}
+	t.TextFrames[f.ID] = strings.Join(values, "/")
+	return nil
+}
+
+// Stores a URL frame in t.URLs keyed by frame ID, or in t.UserURLs keyed by
+// description for WXXX frames (WXX in ID3v2.2). WFED is left out as iTunes
+// encodes it like a text frame. A WXXX frame that can't be decoded is left
+// out and the error returned.
+func keepID3v2URLFrame(t *SimpleTags, f *Frame) error {
+	if f.ID[0] != 'W' || f.ID == "WFED" {
+		return nil
+	}
+	if f.ID == "WXXX" || f.ID == "WXX" {
+		desc, url, err := parseID3v2UserURL(f.Data)
 		if err != nil {
-			return nil, fmt.Errorf("parseID3v2File: %s", err)
+			return err
+		}
+		if t.UserURLs == nil {
+			t.UserURLs = map[string]string{}
 		}
-		tag := string(b)
-		size, err := parseSize(lreader)
+		t.UserURLs[desc] = url
+		return nil
+	}
+	if t.URLs == nil {
+		t.URLs = map[string]string{}
+	}
+	t.URLs[f.ID] = parseID3v2URL(f.Data)
+	return nil
+}
+
+// Frame returns the value of the text frame with the given ID, as it
+// appears in the tag, e.g. "TKEY" or "TKE" in ID3v2.2 tags. Frames with
+// several values, as allowed by ID3v2.4, have them joined by "/".
+func (t *SimpleTags) Frame(id string) (string, bool) {
+	v, ok := t.TextFrames[id]
+	return v, ok
+}
+
+// A single ID3v2 frame with its body undecoded.
+type Frame struct {
+	ID    string
+	Flags [2]byte
+
+	// Unsynchronized reports whether the ID3v2.4 frame flag, or the tag's
+	// unsynchronization flag, was set, in which case Data has already been
+	// de-unsynchronized.
+	Unsynchronized bool
+
+	Data []byte
+}
+
+// ID3v2.4 frame format flags.
+//
+// Refer to section 4.1.2 of http://id3.org/id3v2.4.0-structure
+const (
+	id3v24FrameUnsynchronized = 0x02
+	id3v24FrameDataLength     = 0x01
+)
+
+// ReadAllFrames reads every frame of the ID3v2 tag at the front of reader,
+// mapped or not. Unsynchronized tags and ID3v2.4 frames are decoded and any
+// data length indicator is stripped from their bodies.
+func ReadAllFrames(reader io.Reader) (*ID3v2Header, []Frame, error) {
+	return readAllFrames(reader, &Options{})
+}
+
+// Like ReadAllFrames but parses according to opts.
+func readAllFrames(reader io.Reader, opts *Options) (*ID3v2Header, []Frame, error) {
+	buf := bufio.NewReader(reader)
+	header, err := parseID3v2Header(buf)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parseHeader: %s", err)
+	}
+	if header.Version < 2 || header.Version > 4 {
+		return nil, nil, fmt.Errorf("Unrecognized ID3v2 version: %d", header.Version)
+	}
+	if header.Experimental && opts.RejectExperimental {
+		return nil, nil, ErrExperimental
+	}
+
+	var frames []Frame
+	_, _, err = walkID3v2Frames(buf, header, opts, func(f *Frame, offset int) error {
+		frames = append(frames, *f)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, frames, nil
+}
+
+// Calls fn with each frame of the tag whose header has just been read from
+// reader, along with the frame's offset from the start of the tag header.
+// Returns the end of the last frame relative to the end of the header and
+// the extended header, if any. In tags unsynchronized as a whole, offsets
+// refer to the decoded tag.
+func walkID3v2Frames(reader *bufio.Reader, header *ID3v2Header, opts *Options, fn func(f *Frame, offset int) error) (int, *ExtendedHeader, error) {
+	// The whole tag is read up front so that a frame can be re-read when its
+	// size turns out to be wrong.
+	body, err := ioutil.ReadAll(io.LimitReader(reader, int64(header.Size)))
+	if err != nil {
+		return 0, nil, fmt.Errorf("parseID3v2File: %s", err)
+	}
+
+	// Before ID3v2.4 the unsynchronization flag covers everything after the
+	// header, while ID3v2.4 applies it to each frame.
+	unsynchronized := header.Unsynchronization && header.Version < 4
+	// Set when the bytes read so far end in 0xFF, in which case a 0x00
+	// starting the next chunk read by extend is its escape.
+	trailingFF := false
+	if unsynchronized {
+		trailingFF = len(body) > 0 && body[len(body)-1] == 0xff
+		body = removeUnsynchronization(body)
+	}
+
+	var ext *ExtendedHeader
+	pos := 0
+	if header.Extended && header.Version > 2 {
+		if ext, pos, err = parseID3v2ExtendedHeader(body, header.Version); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	// Frames past the declared size of the tag are read on demand, making
+	// sure body holds at least n bytes where possible. The size of a tag
+	// with a footer is trusted as the footer follows it. Unsynchronized
+	// bytes shrink as they are decoded so more may be read than once.
+	extend := func(n int) {
+		for n > len(body) && opts.ReadPastHeaderSize && !header.Footer {
+			more, _ := ioutil.ReadAll(io.LimitReader(reader, int64(n-len(body))))
+			if len(more) == 0 {
+				return
+			}
+			if unsynchronized {
+				escaped := trailingFF && more[0] == 0
+				trailingFF = more[len(more)-1] == 0xff
+				if escaped {
+					more = more[1:]
+				}
+				more = removeUnsynchronization(more)
+			}
+			body = append(body, more...)
+		}
+	}
+
+	headerLen := id3v2FrameHeaderLength(header.Version)
+	tagLen := 4
+	if header.Version == 2 {
+		tagLen = 3
+	}
+	for {
+		extend(pos + headerLen)
+		if !hasID3v2FrameID(body[pos:], tagLen, opts.CaseInsensitiveFrames) {
+			break
+		}
+		// offsets are reported relative to the start of the tag header
+		offset := 10 + pos
+		if header.Version == 3 && opts.LenientFrameFlags {
+			if id, data, ok := readFlaglessID3v23Frame(body, pos); ok {
+				pos += 8 + len(data)
+				if opts.CaseInsensitiveFrames {
+					id = strings.ToUpper(id)
+				}
+				if err := fn(&Frame{ID: id, Data: data}, offset); err != nil {
+					return 0, nil, err
+				}
+				continue
+			}
+		}
+		if len(body)-pos >= headerLen {
+			extend(pos + headerLen + id3v2FrameSize(body[pos:], header.Version))
+		}
+		id, flags, data, err := readID3v2FrameAt(body, pos, header.Version, opts.CaseInsensitiveFrames)
 		if err != nil {
-			return nil, err
-		}
-		// skip frame flags (only present in 2.3 and v2.4)
-		if header.Version == 3 || header.Version == 4 {
-			skipBytes(lreader, 2)
-		}
-		id, ok := tagMap[tag]
-		if ok != true {
-			// skip over unknown tags
-			skipBytes(lreader, size)
-			continue
-		}
-		if id == "genre" {
-			tags[id], err = readID3v2Genre(lreader, size)
-			if err != nil {
-				return nil, err
+			return 0, nil, fmt.Errorf("frame at offset %d: %w", offset, err)
+		}
+		pos += headerLen + len(data)
+
+		if opts.CaseInsensitiveFrames {
+			id = strings.ToUpper(id)
+		}
+		f := &Frame{ID: id, Flags: flags, Data: data}
+		if header.Version == 4 {
+			if flags[1]&id3v24FrameUnsynchronized != 0 || header.Unsynchronization {
+				f.Unsynchronized = true
+				f.Data = removeUnsynchronization(f.Data)
 			}
-		} else {
-			tags[id], err = readID3v2String(lreader, size)
-			if err != nil {
-				return nil, err
+			if flags[1]&id3v24FrameDataLength != 0 {
+				if len(f.Data) < 4 {
+					return 0, nil, fmt.Errorf("frame %s at offset %d: missing data length indicator", id, offset)
+				}
+				f.Data = f.Data[4:]
 			}
 		}
+		if err := fn(f, offset); err != nil {
+			return 0, nil, err
+		}
+	}
+	return pos, ext, nil
+}
+
+// Returns the size of the frame whose header starts data.
+func id3v2FrameSize(data []byte, version int) int {
+	switch version {
+	case 2:
+		return int(data[3])<<16 | int(data[4])<<8 | int(data[5])
+	case 3:
+		return int(binary.BigEndian.Uint32(data[4:]))
+	}
+	return int(parseID3v2Size(data[4:8]))
+}
+
+// Reads the ID3v2.3 frame at pos in body as one written without its flags,
+// as some encoders do for the last frame. That's only assumed when reading
+// the flags would make the body overrun the tag and the bytes in their
+// place aren't valid flags.
+func readFlaglessID3v23Frame(body []byte, pos int) (string, []byte, bool) {
+	if pos+10 > len(body) {
+		return "", nil, false
+	}
+	size := int(binary.BigEndian.Uint32(body[pos+4 : pos+8]))
+	end := pos + 8 + size
+	if size < 0 || end+2 <= len(body) || end > len(body) {
+		return "", nil, false
+	}
+	// %abc00000 %ijk00000
+	if body[pos+8]&0x1f == 0 && body[pos+9]&0x1f == 0 {
+		return "", nil, false
+	}
+	return string(body[pos : pos+4]), body[pos+8 : end], true
+}
+
+// Reverses unsynchronization by dropping the 0x00 inserted after every 0xFF.
+//
+// Refer to section 6.1 of http://id3.org/id3v2.4.0-structure
+func removeUnsynchronization(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		out = append(out, data[i])
+		if data[i] == 0xff && i+1 < len(data) && data[i+1] == 0 {
+			i++
+		}
+	}
+	return out
+}
+
+// Parses the extended header at the start of body, returning it along with
+// its length. In ID3v2.3 it holds a plain size excluding itself, 2 flag
+// bytes, the padding size and the CRC. In ID3v2.4 it holds a sync-safe size
+// including itself, a count of flag bytes that is always 1, the flags and
+// then the length and data of each flag that has data.
+func parseID3v2ExtendedHeader(body []byte, version int) (*ExtendedHeader, int, error) {
+	if len(body) < 6 {
+		return nil, 0, fmt.Errorf("truncated extended header")
+	}
+	h := &ExtendedHeader{}
+	if version == 3 {
+		size := 4 + int(binary.BigEndian.Uint32(body))
+		if size < 10 || size > len(body) {
+			return nil, 0, fmt.Errorf("extended header size %d overruns the tag", size)
+		}
+		if body[4]&0x80 != 0 && size >= 14 {
+			h.HasCRC = true
+			h.CRC = binary.BigEndian.Uint32(body[10:14])
+		}
+		return h, size, nil
+	}
+
+	size := int(parseID3v2Size(body[:4]))
+	if size < 6 || size > len(body) {
+		return nil, 0, fmt.Errorf("extended header size %d overruns the tag", size)
+	}
+	flags := body[5]
+	data := body[6:size]
+	next := func() []byte {
+		if len(data) == 0 || 1+int(data[0]) > len(data) {
+			data = nil
+			return nil
+		}
+		d := data[1 : 1+int(data[0])]
+		data = data[1+len(d):]
+		return d
+	}
+	if flags&0x40 != 0 {
+		h.Update = true
+		next()
+	}
+	if flags&0x20 != 0 {
+		// a 35 bit sync-safe integer
+		if d := next(); len(d) == 5 {
+			h.HasCRC = true
+			h.CRC = uint32(d[0])<<28 | uint32(d[1])<<21 | uint32(d[2])<<14 | uint32(d[3])<<7 | uint32(d[4])
+		}
+	}
+	if flags&0x10 != 0 {
+		if d := next(); len(d) == 1 {
+			h.HasRestrictions = true
+			h.Restrictions = d[0]
+		}
+	}
+	return h, size, nil
+}
+
+// Reads the frame at pos in body. Plenty of ID3v2.4 tags are written with
+// ID3v2.3's plain frame sizes and the odd ID3v2.3 tag with sync-safe ones, so
+// if the frame isn't followed by another frame, padding or the end of the
+// tag its size is re-read using the other version's size codec.
+func readID3v2FrameAt(body []byte, pos int, version int, lowercase bool) (string, [2]byte, []byte, error) {
+	read := func(version int) (string, [2]byte, []byte, error) {
+		return ReadFrame(bufio.NewReaderSize(bytes.NewReader(body[pos:]), 16), version)
+	}
+	headerLen := id3v2FrameHeaderLength(version)
+	tagLen := headerLen - 6
+
+	tag, flags, data, err := read(version)
+	if version == 2 || (err == nil && isID3v2FrameBoundary(body, pos+headerLen+len(data), tagLen, lowercase)) {
+		return tag, flags, data, err
+	}
+	if altTag, altFlags, altData, altErr := read(7 - version); altErr == nil &&
+		isID3v2FrameBoundary(body, pos+headerLen+len(altData), tagLen, lowercase) {
+		return altTag, altFlags, altData, nil
+	}
+	return tag, flags, data, err
+}
+
+// Reports whether a frame, padding or the end of the tag begins at pos.
+func isID3v2FrameBoundary(body []byte, pos int, tagLen int, lowercase bool) bool {
+	if pos == len(body) {
+		return true
+	}
+	if pos > len(body) {
+		return false
+	}
+	return body[pos] == 0 || hasID3v2FrameID(body[pos:], tagLen, lowercase)
+}
+
+// Like hasID3v2Frame but also accepts lowercase IDs if lowercase is set.
+func hasID3v2FrameID(data []byte, tagLen int, lowercase bool) bool {
+	if lowercase && len(data) >= tagLen {
+		data = bytes.ToUpper(data[:tagLen])
+	}
+	return hasID3v2Frame(data, tagLen)
+}
+
+// Length of a frame header: the ID, size and (except for v2.2) flags.
+func id3v2FrameHeaderLength(version int) int {
+	if version == 2 {
+		return 6
+	}
+	return 10
+}
+
+// Decodes the body of frame f, known by its name id in the version specific
+// tag map. Structured frames are stored in t and text frames in tags.
+func parseID3v2Frame(t *SimpleTags, tags map[string]string, id string, f *Frame, opts *Options) error {
+	var err error
+	data := f.Data
+	if opts.LenientEncoding {
+		data = fixUTF8BOM(data)
+	}
+	switch id {
+	case "genre":
+		if tags[id], t.GenreRaw, err = parseID3v2Genre(data, opts.genres()); err == nil {
+			t.Genres, err = parseID3v2Genres(data, opts.genres())
+		}
+	case "artist":
+		var artists []string
+		if artists, err = parseID3v2Strings(data); err == nil {
+			t.Artists = artists
+			tags[id] = strings.Join(artists, "/")
+		}
+	case "comments":
+		var c *Comment
+		if c, err = parseID3v2Comment(data); err == nil {
+			t.Comments = append(t.Comments, *c)
+			// comments with a description belong to applications
+			if _, ok := tags[id]; !ok && c.Description == "" {
+				tags[id] = c.Text
+			}
+		}
+	case "lyrics":
+		var l *UnsyncLyrics
+		if l, err = parseID3v2Lyrics(data); err == nil {
+			t.Lyrics = append(t.Lyrics, *l)
+		}
+	case "usertext":
+		var desc, value string
+		if desc, value, err = parseID3v2UserText(data); err == nil {
+			if t.UserText == nil {
+				t.UserText = map[string]string{}
+			}
+			t.UserText[desc] = value
+		}
+	case "credits":
+		var c []Credit
+		if c, err = parseID3v2Credits(data); err == nil {
+			t.Credits = append(t.Credits, c...)
+		}
+	case "encryption":
+		// the audio can't be decrypted but callers may want to know
+		t.Encrypted = true
+		if t.RawFrames == nil {
+			t.RawFrames = map[string][]byte{}
+		}
+		t.RawFrames[f.ID] = data
+	case "picture":
+		var p *Picture
+		if len(f.ID) == 3 {
+			p, err = parseID3v22Picture(data)
+		} else {
+			p, err = parseID3v2Picture(data)
+		}
+		if err != nil {
+			return err
+		}
+		t.Pictures = append(t.Pictures, *p)
+	case "seek":
+		if len(data) < 4 {
+			return fmt.Errorf("seek frame too short: %d bytes", len(data))
+		}
+		t.SeekOffset = int64(binary.BigEndian.Uint32(data))
+	case "relativevolume":
+		var ident string
+		var gain float64
+		var ok bool
+		if ident, gain, ok, err = parseID3v2RelativeVolume(data); err != nil || !ok {
+			return err
+		}
+		if t.RelativeVolume == nil {
+			t.RelativeVolume = map[string]float64{}
+		}
+		t.RelativeVolume[ident] = gain
+	case "ownership":
+		t.Ownership, err = parseID3v2Ownership(data)
+	case "commercial":
+		var c *Commercial
+		if c, err = parseID3v2Commercial(data); err == nil {
+			t.Commercial = append(t.Commercial, *c)
+		}
+	default:
+		tags[id], err = parseID3v2String(data)
 	}
-	return tags, nil
+	return err
 }
@@ -27,18 +27,25 @@ var ID3v22Tags = map[string]string{
 	"TCM": "composer",
 	"TP3": "conductor",
 	"TCR": "copyright",
+	"IPL": "credits",
 	"TDA": "date",
 	"TPA": "disc",
 	"TEN": "encodedby",
 	"TSS": "encoder",
+	"CRA": "encryption",
 	"TCO": "genre",
 	"TT1": "group",
 	"TLA": "language",
+	"ULT": "lyrics",
 	"TMT": "media",
 	"TOA": "originalartist",
+	"TOR": "originalrelease",
+	"PIC": "picture",
 	"TPB": "publisher",
+	"TSI": "size",
 	"TT2": "title",
 	"TRK": "track",
+	"TXX": "usertext",
 	"TXT": "writer",
 	"TYE": "year",
 }
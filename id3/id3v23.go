@@ -25,22 +25,35 @@ var ID3v23Tags = map[string]string{
 	"TPE2": "band",
 	"TBPM": "bpm",
 	"COMM": "comments",
+	"COMR": "commercial",
 	"TCOM": "composer",
 	"TPE3": "conductor",
 	"TCOP": "copyright",
+	"IPLS": "credits",
 	"TDAT": "date",
 	"TPOS": "disc",
 	"TENC": "encodedby",
 	"TSSE": "encoder",
+	"AENC": "encryption",
 	"TCON": "genre",
 	"TIT1": "group",
+	"TKWD": "keywords",
 	"TLAN": "language",
 	"TLEN": "length",
+	"USLT": "lyrics",
 	"TMED": "media",
 	"TOPE": "originalartist",
+	"TORY": "originalrelease",
+	"OWNE": "ownership",
+	"APIC": "picture",
+	"TDES": "podcastdescription",
+	"WFED": "podcastfeed",
+	"TGID": "podcastid",
 	"TPUB": "publisher",
+	"TSIZ": "size",
 	"TIT2": "title",
 	"TRCK": "track",
+	"TXXX": "usertext",
 	"TEXT": "writer",
 	"TYER": "year",
 }
@@ -48,6 +61,8 @@ var ID3v23Tags = map[string]string{
 // ID3 v2.3 doesn't use sync-safe frame sizes: read in as a regular big endian number.
 func parseID3v23FrameSize(reader *bufio.Reader) (int, error) {
 	var size int32
-	binary.Read(reader, binary.BigEndian, &size)
+	if err := binary.Read(reader, binary.BigEndian, &size); err != nil {
+		return -1, err
+	}
 	return int(size), nil
 }
@@ -16,30 +16,50 @@ package id3
 
 import (
 	"bufio"
+	"fmt"
+	"time"
 )
 
 var ID3v24Tags = map[string]string{
 	"TALB": "album",
 	"TPE1": "artist",
 	"TPE2": "band",
-	"TBMP": "bpm",
+	"TBPM": "bpm",
 	"COMM": "comments",
+	"COMR": "commercial",
 	"TCOM": "composer",
 	"TPE3": "conductor",
 	"TCOP": "copyright",
+	"TIPL": "credits",
+	"TMCL": "credits",
 	"TDAT": "date",
 	"TPOS": "disc",
 	"TENC": "encodedby",
 	"TSSE": "encoder",
+	"AENC": "encryption",
 	"TCON": "genre",
 	"TIT1": "group",
+	"TKWD": "keywords",
 	"TLAN": "language",
 	"TLEN": "length",
+	"USLT": "lyrics",
 	"TMED": "media",
+	"TMOO": "mood",
 	"TOPE": "originalartist",
+	"TDOR": "originalrelease",
+	"OWNE": "ownership",
+	"APIC": "picture",
+	"TDES": "podcastdescription",
+	"WFED": "podcastfeed",
+	"TGID": "podcastid",
+	"TPRO": "producednotice",
 	"TPUB": "publisher",
+	"RVA2": "relativevolume",
+	"TDRL": "releasetime",
+	"SEEK": "seek",
 	"TIT2": "title",
 	"TRCK": "track",
+	"TXXX": "usertext",
 	"TEXT": "writer",
 	"TDRC": "year",
 }
@@ -52,3 +72,69 @@ func parseID3v24FrameSize(reader *bufio.Reader) (int, error) {
 	}
 	return int(parseID3v2Size(size)), nil
 }
+
+// Layouts of the ID3v2.4 timestamp, a subset of ISO 8601, ordered from least
+// to most precise.
+var id3v24TimestampLayouts = []string{
+	"2006",
+	"2006-01",
+	"2006-01-02",
+	"2006-01-02T15",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+}
+
+// TimestampPrecision is the smallest component given by a timestamp.
+type TimestampPrecision int
+
+// The zero TimestampPrecision means there is no timestamp.
+const (
+	PrecisionYear TimestampPrecision = iota + 1
+	PrecisionMonth
+	PrecisionDay
+	PrecisionHour
+	PrecisionMinute
+	PrecisionSecond
+)
+
+// Parses an ID3v2.4 timestamp, which may be truncated to any precision
+// between a year and seconds: yyyy[-MM[-dd[THH[:mm[:ss]]]]].
+//
+// Refer to section 4 of http://id3.org/id3v2.4.0-structure
+func parseID3v2Timestamp(s string) (time.Time, error) {
+	t, _, err := parseID3v2TimestampPrecision(s)
+	return t, err
+}
+
+// Like parseID3v2Timestamp but also returns the timestamp's precision.
+func parseID3v2TimestampPrecision(s string) (time.Time, TimestampPrecision, error) {
+	for i, layout := range id3v24TimestampLayouts {
+		if len(s) == len(layout) {
+			t, err := time.Parse(layout, s)
+			if err != nil {
+				return time.Time{}, 0, err
+			}
+			return t, PrecisionYear + TimestampPrecision(i), nil
+		}
+	}
+	return time.Time{}, 0, fmt.Errorf("invalid timestamp: %q", s)
+}
+
+// Formats t as an ID3v2.4 timestamp, omitting trailing components that
+// are unset.
+func formatID3v2Timestamp(t time.Time) string {
+	var i int
+	switch {
+	case t.Second() != 0:
+		i = 5
+	case t.Minute() != 0:
+		i = 4
+	case t.Hour() != 0:
+		i = 3
+	case t.Day() != 1:
+		i = 2
+	case t.Month() != time.January:
+		i = 1
+	}
+	return t.Format(id3v24TimestampLayouts[i])
+}
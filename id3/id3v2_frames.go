@@ -0,0 +1,343 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A Comment is a decoded COMM frame (COM in ID3v2.2).
+type Comment struct {
+	Language    string
+	Description string
+	Text        string
+}
+
+// Unsynchronised lyrics or a text transcription.
+type UnsyncLyrics struct {
+	Language   string
+	Descriptor string
+	Text       string
+}
+
+// CommentsByLang returns the comments whose 3-letter language code matches
+// lang, ignoring case.
+func (t *SimpleTags) CommentsByLang(lang string) []Comment {
+	var comments []Comment
+	for _, c := range t.Comments {
+		if strings.EqualFold(c.Language, lang) {
+			comments = append(comments, c)
+		}
+	}
+	return comments
+}
+
+// DisplayComment returns the comment to show to a user: the first one
+// without a description in language lang, then the first without a
+// description in any language and then the first comment. Comments with a
+// description usually hold data for applications, such as iTunNORM. With
+// no comment frames it returns Comment, which may come from the ID3v1 tag.
+func (t *SimpleTags) DisplayComment(lang string) string {
+	if len(t.Comments) == 0 {
+		return t.Comment
+	}
+	for _, c := range t.Comments {
+		if c.Description == "" && strings.EqualFold(c.Language, lang) {
+			return c.Text
+		}
+	}
+	for _, c := range t.Comments {
+		if c.Description == "" {
+			return c.Text
+		}
+	}
+	return t.Comments[0].Text
+}
+
+// Parses a TXXX frame: an encoding byte, a terminated description and the
+// value, both in the frame's encoding.
+//
+// Refer to section 4.2.6 of http://id3.org/id3v2.4.0-frames
+func parseID3v2UserText(data []byte) (desc string, value string, err error) {
+	if len(data) < 1 {
+		return "", "", fmt.Errorf("user text frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	d, v := splitID3v2String(encoding, data[1:])
+	desc, err = parseID3v2EncodedString(encoding, d)
+	if err != nil {
+		return "", "", err
+	}
+	value, err = parseID3v2EncodedString(encoding, v)
+	if err != nil {
+		return "", "", err
+	}
+	return desc, value, nil
+}
+
+// Decodes the body of a URL frame, which is an ISO-8859-1 string without an
+// encoding byte. Anything after a terminator is ignored.
+//
+// Refer to section 4.3.1 of http://id3.org/id3v2.4.0-frames
+func parseID3v2URL(data []byte) string {
+	url, _ := splitID3v2String(0, data)
+	return ISO8859_1ToUTF8(url)
+}
+
+// Parses a WXXX frame: an encoding byte, a terminated description in that
+// encoding and then the URL, which is always ISO-8859-1.
+//
+// Refer to section 4.3.2 of http://id3.org/id3v2.4.0-frames
+func parseID3v2UserURL(data []byte) (desc string, url string, err error) {
+	if len(data) < 1 {
+		return "", "", fmt.Errorf("user URL frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	d, u := splitID3v2String(encoding, data[1:])
+	desc, err = parseID3v2EncodedString(encoding, d)
+	if err != nil {
+		return "", "", err
+	}
+	return desc, parseID3v2URL(u), nil
+}
+
+// A Credit pairs a role, such as an instrument or "producer", with the
+// name of the person credited for it.
+type Credit struct {
+	Role string
+	Name string
+}
+
+// Parses a TMCL or TIPL frame (IPLS in ID3v2.3): an encoding byte followed
+// by terminated strings alternating between a role and a name.
+//
+// Refer to section 4.2.2 of http://id3.org/id3v2.4.0-frames
+func parseID3v2Credits(data []byte) ([]Credit, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("credits frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	var values []string
+	for rest := data[1:]; len(rest) > 0; {
+		var b []byte
+		b, rest = splitID3v2String(encoding, rest)
+		v, err := parseID3v2EncodedString(encoding, b)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+
+	credits := make([]Credit, 0, (len(values)+1)/2)
+	for i := 0; i < len(values); i += 2 {
+		c := Credit{Role: values[i]}
+		if i+1 < len(values) {
+			c.Name = values[i+1]
+		}
+		credits = append(credits, c)
+	}
+	return credits, nil
+}
+
+// Ownership is a decoded OWNE frame. Price is a 3 letter currency code
+// immediately followed by the amount, e.g. "USD9.99", and Date is formatted
+// as YYYYMMDD.
+type Ownership struct {
+	Price  string
+	Date   string
+	Seller string
+}
+
+// Commercial is a decoded COMR frame. Price may hold several prices separated
+// by "/", each formatted like Ownership.Price, and ValidUntil is formatted as
+// YYYYMMDD. ReceivedAs describes how the audio is delivered, e.g. 0x01 for a
+// standard CD album.
+type Commercial struct {
+	Price        string
+	ValidUntil   string
+	ContactURL   string
+	ReceivedAs   byte
+	Seller       string
+	Description  string
+	LogoMIMEType string
+	Logo         []byte
+}
+
+// Splits frame data at the first string terminator for the given encoding.
+// UTF-16 strings are terminated by two zero bytes on a 2-byte boundary while
+// all other encodings use a single zero byte. The terminator is dropped and
+// rest is nil if no terminator was found.
+func splitID3v2String(encoding byte, data []byte) (str []byte, rest []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return data[:i], data[i+2:]
+			}
+		}
+		return data, nil
+	}
+
+	i := bytes.IndexByte(data, 0)
+	if i < 0 {
+		return data, nil
+	}
+	return data[:i], data[i+1:]
+}
+
+// Decodes a string that shares its encoding byte with the rest of the frame.
+func parseID3v2EncodedString(encoding byte, data []byte) (string, error) {
+	return parseID3v2String(append([]byte{encoding}, data...))
+}
+
+// Decodes a text frame holding one or more null separated values, as
+// allowed by ID3v2.4. A trailing terminator does not start another value.
+func parseID3v2Strings(data []byte) ([]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var values []string
+	encoding, rest := data[0], data[1:]
+	for len(rest) > 0 {
+		var str []byte
+		str, rest = splitID3v2String(encoding, rest)
+		s, err := parseID3v2EncodedString(encoding, str)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// Parses a COMM frame: an encoding byte, a 3 byte language code, a
+// terminated description and finally the comment text.
+//
+// Refer to section 4.10 of http://id3.org/id3v2.4.0-frames
+func parseID3v2Comment(data []byte) (*Comment, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("comment frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	c := &Comment{Language: string(data[1:4])}
+	desc, text := splitID3v2String(encoding, data[4:])
+
+	var err error
+	c.Description, err = parseID3v2EncodedString(encoding, desc)
+	if err != nil {
+		return nil, err
+	}
+	c.Text, err = parseID3v2EncodedString(encoding, text)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Parses a USLT frame, which is laid out like a COMM frame: an encoding
+// byte, a 3 byte language code, a terminated descriptor and then the
+// lyrics.
+//
+// Refer to section 4.8 of http://id3.org/id3v2.4.0-frames
+func parseID3v2Lyrics(data []byte) (*UnsyncLyrics, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("lyrics frame too short: %d bytes", len(data))
+	}
+	c, err := parseID3v2Comment(data)
+	if err != nil {
+		return nil, err
+	}
+	return &UnsyncLyrics{Language: c.Language, Descriptor: c.Description, Text: c.Text}, nil
+}
+
+// Parses an OWNE frame: an encoding byte, a terminated ISO-8859-1 price, an 8
+// character purchase date and the seller's name in the frame's encoding.
+//
+// Refer to section 4.22 of http://id3.org/id3v2.4.0-frames
+func parseID3v2Ownership(data []byte) (*Ownership, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("ownership frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	price, rest := splitID3v2String(0, data[1:])
+	if len(rest) < 8 {
+		return nil, fmt.Errorf("ownership frame missing purchase date")
+	}
+
+	o := &Ownership{
+		Price: ISO8859_1ToUTF8(price),
+		Date:  ISO8859_1ToUTF8(rest[:8]),
+	}
+	var err error
+	o.Seller, err = parseID3v2EncodedString(encoding, rest[8:])
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Parses a COMR frame: an encoding byte, a terminated ISO-8859-1 price
+// string, an 8 character expiry date, a terminated ISO-8859-1 contact URL, a
+// "received as" byte, the seller and description in the frame's encoding and
+// finally an optional seller logo preceded by its terminated MIME type.
+//
+// Refer to section 4.23 of http://id3.org/id3v2.4.0-frames
+func parseID3v2Commercial(data []byte) (*Commercial, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("commercial frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	price, rest := splitID3v2String(0, data[1:])
+	if len(rest) < 8 {
+		return nil, fmt.Errorf("commercial frame missing valid until date")
+	}
+	c := &Commercial{
+		Price:      ISO8859_1ToUTF8(price),
+		ValidUntil: ISO8859_1ToUTF8(rest[:8]),
+	}
+
+	url, rest := splitID3v2String(0, rest[8:])
+	if len(rest) < 1 {
+		return nil, fmt.Errorf("commercial frame missing received as")
+	}
+	c.ContactURL = ISO8859_1ToUTF8(url)
+	c.ReceivedAs = rest[0]
+
+	seller, rest := splitID3v2String(encoding, rest[1:])
+	desc, rest := splitID3v2String(encoding, rest)
+	var err error
+	c.Seller, err = parseID3v2EncodedString(encoding, seller)
+	if err != nil {
+		return nil, err
+	}
+	c.Description, err = parseID3v2EncodedString(encoding, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rest) > 0 {
+		mime, logo := splitID3v2String(0, rest)
+		c.LogoMIMEType = ISO8859_1ToUTF8(mime)
+		c.Logo = logo
+	}
+	return c, nil
+}
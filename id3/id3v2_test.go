@@ -0,0 +1,1325 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestComments(t *testing.T) {
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "COMM", []byte("\x00engNote\x00Hello")),
+		buildID3v2Frame(3, "COMM", []byte("\x01eng\xff\xfeD\x00\x00\x00\xff\xfeH\x00i\x00")))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := []Comment{
+		{"eng", "Note", "Hello"},
+		{"eng", "D", "Hi"},
+	}
+	if len(f.Comments) != len(expected) {
+		t.Fatalf("Comments: expected %d got %d", len(expected), len(f.Comments))
+	}
+	for i, c := range expected {
+		if f.Comments[i] != c {
+			t.Errorf("Comments[%d]: expected %+v got %+v", i, c, f.Comments[i])
+		}
+	}
+}
+
+func TestExperimentalTag(t *testing.T) {
+	tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")))
+	tag[5] |= 1 << 5
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if !f.Header.Experimental || f.Title != "Title" {
+		t.Errorf("expected an experimental tag titled 'Title' got %+v", f)
+	}
+	if len(f.Warnings) != 1 || f.Warnings[0] != "tag marked experimental" {
+		t.Errorf("Warnings: got %q", f.Warnings)
+	}
+
+	if _, err := ReadWithOptions(bytes.NewReader(tag), Options{RejectExperimental: true}); err != ErrExperimental {
+		t.Errorf("expected ErrExperimental got %v", err)
+	}
+	if _, err := ReadFileStrict(bytes.NewReader(tag)); err != nil {
+		t.Errorf("ReadFileStrict: %s", err)
+	}
+
+	// strict mode accepts the tag but still flags it
+	if f, err = ReadStrict(bytes.NewReader(tag)); err != nil {
+		t.Fatalf("ReadStrict: %s", err)
+	}
+	if f.Title != "Title" || len(f.Warnings) != 1 || f.Warnings[0] != "tag marked experimental" {
+		t.Errorf("ReadStrict: expected a warning got %q", f.Warnings)
+	}
+}
+
+func TestMultipleArtists(t *testing.T) {
+	for _, data := range []string{
+		"\x03Daft Punk\x00Pharrell Williams",
+		"\x03Daft Punk\x00Pharrell Williams\x00",
+		// each UTF-16 value has its own BOM
+		"\x01\xff\xfeD\x00a\x00f\x00t\x00 \x00P\x00u\x00n\x00k\x00\x00\x00" +
+			"\xff\xfeP\x00h\x00a\x00r\x00r\x00e\x00l\x00l\x00 \x00W\x00i\x00l\x00l\x00i\x00a\x00m\x00s\x00",
+	} {
+		tag := buildID3v2Tag(4, buildID3v2Frame(4, "TPE1", []byte(data)))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%q: Read: %s", data, err)
+		}
+		expected := []string{"Daft Punk", "Pharrell Williams"}
+		if !reflect.DeepEqual(f.Artists, expected) {
+			t.Errorf("%q: expected %q got %q", data, expected, f.Artists)
+		}
+		if f.Artist != "Daft Punk/Pharrell Williams" {
+			t.Errorf("%q: expected Artist 'Daft Punk/Pharrell Williams' got %q", data, f.Artist)
+		}
+		if got := f.JoinedArtists(); got != "Daft Punk; Pharrell Williams" {
+			t.Errorf("%q: expected JoinedArtists 'Daft Punk; Pharrell Williams' got %q", data, got)
+		}
+	}
+}
+
+func TestTextFields(t *testing.T) {
+	ids := map[int][]string{
+		2: {"TCM", "TCR", "TEN", "TSS", "TLA", "TXT", "TOA", "TMT"},
+		3: {"TCOM", "TCOP", "TENC", "TSSE", "TLAN", "TEXT", "TOPE", "TMED"},
+		4: {"TCOM", "TCOP", "TENC", "TSSE", "TLAN", "TEXT", "TOPE", "TMED"},
+	}
+	values := []string{"Composer", "2009 Label", "Encoded By", "LAME 3.99", "eng", "Writer", "Original Artist", "CD"}
+	expected := SimpleTags{
+		Composer:       "Composer",
+		Copyright:      "2009 Label",
+		EncodedBy:      "Encoded By",
+		Encoder:        "LAME 3.99",
+		Language:       "eng",
+		Writer:         "Writer",
+		OriginalArtist: "Original Artist",
+		Media:          "CD",
+	}
+	check := func(name string, f *SimpleTags) {
+		got := SimpleTags{
+			Composer:       f.Composer,
+			Copyright:      f.Copyright,
+			EncodedBy:      f.EncodedBy,
+			Encoder:        f.Encoder,
+			Language:       f.Language,
+			Writer:         f.Writer,
+			OriginalArtist: f.OriginalArtist,
+			Media:          f.Media,
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %+v got %+v", name, expected, got)
+		}
+	}
+
+	for version := 2; version <= 4; version++ {
+		var frames [][]byte
+		for i, id := range ids[version] {
+			frames = append(frames, buildID3v2Frame(version, id, []byte("\x00"+values[i])))
+		}
+		f, err := Read(bytes.NewReader(buildID3v2Tag(version, frames...)))
+		if err != nil {
+			t.Fatalf("v2.%d: Read: %s", version, err)
+		}
+		check(fmt.Sprintf("v2.%d", version), f)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTag(&buf, &expected, WriteOptions{}); err != nil {
+		t.Fatalf("WriteTag: %s", err)
+	}
+	f, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	check("round trip", f)
+}
+
+func TestFrame(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TBPM", []byte("\x03128")),
+		buildID3v2Frame(4, "TKEY", []byte("\x03F#m")),
+		buildID3v2Frame(4, "TSRC", []byte("\x03USRC17607839")),
+		buildID3v2Frame(4, "TCMP", []byte("\x031")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Daft Punk\x00Pharrell Williams")),
+		buildID3v2Frame(4, "TXXX", []byte("\x03desc\x00value")),
+		buildID3v2Frame(4, "PCNT", []byte("\x00\x00\x00\x07")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	for id, expected := range map[string]string{
+		"TBPM": "128",
+		"TKEY": "F#m",
+		"TSRC": "USRC17607839",
+		"TCMP": "1",
+		"TPE1": "Daft Punk/Pharrell Williams",
+	} {
+		if v, ok := f.Frame(id); !ok || v != expected {
+			t.Errorf("Frame(%s): expected %q got %q, %t", id, expected, v, ok)
+		}
+	}
+	for _, id := range []string{"TXXX", "PCNT", "TIT2"} {
+		if v, ok := f.Frame(id); ok {
+			t.Errorf("Frame(%s): expected nothing got %q", id, v)
+		}
+	}
+
+	v22 := buildID3v2Tag(2, buildID3v2Frame(2, "TKE", []byte("\x00Am")))
+	if f, err = Read(bytes.NewReader(v22)); err != nil {
+		t.Fatalf("v2.2: Read: %s", err)
+	}
+	if v, ok := f.Frame("TKE"); !ok || v != "Am" {
+		t.Errorf("v2.2: Frame(TKE): expected 'Am' got %q, %t", v, ok)
+	}
+}
+
+func TestURLFrames(t *testing.T) {
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "WOAR", []byte("https://example.com/artist")),
+		buildID3v2Frame(3, "WPUB", []byte("https://example.com/label\x00")),
+		buildID3v2Frame(3, "WXXX", []byte("\x01\xff\xfeS\x00h\x00o\x00p\x00\x00\x00https://example.com/buy")),
+		// a description can't replace the real WOAR
+		buildID3v2Frame(3, "WXXX", []byte("\x00WOAR\x00https://example.com/other")),
+		buildID3v2Frame(3, "WFED", []byte("\x00https://example.com/feed.xml")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := map[string]string{
+		"WOAR": "https://example.com/artist",
+		"WPUB": "https://example.com/label",
+	}
+	if !reflect.DeepEqual(f.URLs, expected) {
+		t.Errorf("URLs: expected %v got %v", expected, f.URLs)
+	}
+	expected = map[string]string{
+		"Shop": "https://example.com/buy",
+		"WOAR": "https://example.com/other",
+	}
+	if !reflect.DeepEqual(f.UserURLs, expected) {
+		t.Errorf("UserURLs: expected %v got %v", expected, f.UserURLs)
+	}
+	if f.PodcastFeed != "https://example.com/feed.xml" {
+		t.Errorf("PodcastFeed: got %q", f.PodcastFeed)
+	}
+
+	v22 := buildID3v2Tag(2,
+		buildID3v2Frame(2, "WAR", []byte("https://example.com/artist")),
+		buildID3v2Frame(2, "WXX", []byte("\x00\x00https://example.com/")))
+	if f, err = Read(bytes.NewReader(v22)); err != nil {
+		t.Fatalf("v2.2: Read: %s", err)
+	}
+	if expected = map[string]string{"WAR": "https://example.com/artist"}; !reflect.DeepEqual(f.URLs, expected) {
+		t.Errorf("v2.2: URLs: expected %v got %v", expected, f.URLs)
+	}
+	if expected = map[string]string{"": "https://example.com/"}; !reflect.DeepEqual(f.UserURLs, expected) {
+		t.Errorf("v2.2: UserURLs: expected %v got %v", expected, f.UserURLs)
+	}
+
+	// a WXXX too short to decode is skipped with a warning
+	bad := buildID3v2Tag(3,
+		buildID3v2Frame(3, "WOAR", []byte("https://example.com/artist")),
+		buildID3v2Frame(3, "WXXX", nil))
+	if f, err = Read(bytes.NewReader(bad)); err != nil {
+		t.Fatalf("bad WXXX: Read: %s", err)
+	}
+	if f.URLs["WOAR"] != "https://example.com/artist" || len(f.UserURLs) != 0 {
+		t.Errorf("bad WXXX: expected only WOAR got %v, %v", f.URLs, f.UserURLs)
+	}
+	if len(f.Warnings) != 1 || !strings.HasPrefix(f.Warnings[0], "frame WXXX at offset 46 skipped") {
+		t.Errorf("bad WXXX: Warnings: got %q", f.Warnings)
+	}
+}
+
+func TestCommentFrames(t *testing.T) {
+	tests := []struct {
+		name     string
+		tag      []byte
+		expected Comment
+	}{
+		{"itunes", buildID3v2Tag(3, buildID3v2Frame(3, "COMM", []byte("\x00eng\x00Ripped from CD"))),
+			Comment{"eng", "", "Ripped from CD"}},
+		{"v2.2", buildID3v2Tag(2, buildID3v2Frame(2, "COM", []byte("\x00engDesc\x00Text"))),
+			Comment{"eng", "Desc", "Text"}},
+		// "A" followed by the terminator and "\u0100" holds a pair of null
+		// bytes that straddles two code units
+		{"utf16", buildID3v2Tag(4, buildID3v2Frame(4, "COMM", []byte("\x01eng\xff\xfeA\x00\x00\x00\xff\xfe\x00\x01"))),
+			Comment{"eng", "A", "\u0100"}},
+	}
+	for _, test := range tests {
+		f, err := Read(bytes.NewReader(test.tag))
+		if err != nil {
+			t.Errorf("%s: Read: %s", test.name, err)
+			continue
+		}
+		if len(f.Comments) != 1 || f.Comments[0] != test.expected {
+			t.Errorf("%s: expected %+v got %+v", test.name, test.expected, f.Comments)
+		}
+	}
+}
+
+func TestMainComment(t *testing.T) {
+	named := buildID3v2Frame(3, "COMM", []byte("\x00engSongs-DB_Preference\x0058"))
+	main := buildID3v2Frame(3, "COMM", []byte("\x00eng\x00Great song"))
+
+	for _, tag := range [][]byte{buildID3v2Tag(3, named, main), buildID3v2Tag(3, main, named)} {
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Comment != "Great song" {
+			t.Errorf("Comment: expected 'Great song' got '%s'", f.Comment)
+		}
+		if len(f.Comments) != 2 {
+			t.Errorf("Comments: expected 2 got %+v", f.Comments)
+		}
+	}
+
+	// a named comment doesn't hide the ID3v1 comment
+	v1 := buildID3v1Tag("Title")
+	copy(v1[97:], "ID3v1 comment")
+	f, err := Read(bytes.NewReader(append(buildID3v2Tag(3, named), v1...)))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Comment != "ID3v1 comment" {
+		t.Errorf("Comment: expected 'ID3v1 comment' got '%s'", f.Comment)
+	}
+}
+
+func TestDisplayComment(t *testing.T) {
+	songsDB := Comment{"eng", "Songs-DB_Preference", "58"}
+	german := Comment{"deu", "", "Notiz"}
+	english := Comment{"eng", "", "Note"}
+	tests := []struct {
+		comments []Comment
+		lang     string
+		expected string
+	}{
+		{[]Comment{songsDB, german, english}, "eng", "Note"},
+		{[]Comment{songsDB, german, english}, "DEU", "Notiz"},
+		{[]Comment{songsDB, german, english}, "fra", "Notiz"},
+		{[]Comment{songsDB}, "eng", "58"},
+		{nil, "eng", "ID3v1 comment"},
+	}
+	for _, test := range tests {
+		f := &SimpleTags{Comments: test.comments, Comment: "ID3v1 comment"}
+		if got := f.DisplayComment(test.lang); got != test.expected {
+			t.Errorf("DisplayComment(%s) of %+v: expected '%s' got '%s'", test.lang, test.comments, test.expected, got)
+		}
+	}
+}
+
+func TestLyrics(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "USLT", []byte("\x03engVerse\x00Ein Bär\nZwei Bären\n")),
+		buildID3v2Frame(4, "USLT", []byte("\x00fraChanson\x00Caf\xe9 au lait\r\nSecond vers")))
+	v22 := buildID3v2Tag(2, buildID3v2Frame(2, "ULT", []byte("\x01eng\xff\xfeD\x00\x00\x00\xff\xfeL\x00a\x00\n\x00")))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := []UnsyncLyrics{
+		{"eng", "Verse", "Ein Bär\nZwei Bären\n"},
+		{"fra", "Chanson", "Café au lait\r\nSecond vers"},
+	}
+	if !reflect.DeepEqual(f.Lyrics, expected) {
+		t.Errorf("expected %+v got %+v", expected, f.Lyrics)
+	}
+
+	f, err = Read(bytes.NewReader(v22))
+	if err != nil {
+		t.Fatalf("v2.2: Read: %s", err)
+	}
+	if len(f.Lyrics) != 1 || f.Lyrics[0] != (UnsyncLyrics{"eng", "D", "La\n"}) {
+		t.Errorf("v2.2: got %+v", f.Lyrics)
+	}
+}
+
+func TestUserText(t *testing.T) {
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TXXX", []byte("\x00replaygain_track_gain\x00-6.50 dB")),
+		buildID3v2Frame(3, "TXXX", []byte("\x01\xff\xfeM\x00B\x00\x00\x00\xff\xfe1\x002\x00")),
+		buildID3v2Frame(3, "TXXX", []byte("\x00MusicBrainz Album Id\x00f3a8e1b0-0000")),
+		buildID3v2Frame(3, "TXXX", []byte("\x00replaygain_track_gain\x00-7.00 dB")))
+	v22 := buildID3v2Tag(2, buildID3v2Frame(2, "TXX", []byte("\x00MusicBrainz Album Id\x00f3a8e1b0-0000")))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	// the last of several frames with the same description wins
+	expected := map[string]string{
+		"replaygain_track_gain": "-7.00 dB",
+		"MB":                    "12",
+		"MusicBrainz Album Id":  "f3a8e1b0-0000",
+	}
+	if !reflect.DeepEqual(f.UserText, expected) {
+		t.Errorf("expected %q got %q", expected, f.UserText)
+	}
+
+	f, err = Read(bytes.NewReader(v22))
+	if err != nil {
+		t.Fatalf("v2.2: Read: %s", err)
+	}
+	if f.UserText["MusicBrainz Album Id"] != "f3a8e1b0-0000" {
+		t.Errorf("v2.2: got %q", f.UserText)
+	}
+}
+
+func TestCommentsByLang(t *testing.T) {
+	f := &SimpleTags{Comments: []Comment{
+		{"eng", "", "English"},
+		{"XXX", "", "Unknown"},
+		{"ENG", "Other", "Shouting"},
+	}}
+
+	comments := f.CommentsByLang("Eng")
+	if len(comments) != 2 || comments[0].Text != "English" || comments[1].Text != "Shouting" {
+		t.Errorf("CommentsByLang(Eng): got %+v", comments)
+	}
+	comments = f.CommentsByLang("xxx")
+	if len(comments) != 1 || comments[0].Text != "Unknown" {
+		t.Errorf("CommentsByLang(xxx): got %+v", comments)
+	}
+	if comments = f.CommentsByLang("deu"); comments != nil {
+		t.Errorf("CommentsByLang(deu): expected none got %+v", comments)
+	}
+}
+
+func TestOwnershipAndCommercial(t *testing.T) {
+	comr := []byte("\x00USD9.99/EUR8.99\x0020121231http://shop.example\x00\x01Shop\x00A fine album\x00image/png\x00LOGO")
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "OWNE", []byte("\x03USD9.99\x0020120115Record Shop")),
+		buildID3v2Frame(4, "COMR", comr))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+
+	o := f.Ownership
+	if o == nil {
+		t.Fatal("Ownership: expected frame")
+	}
+	if o.Price != "USD9.99" || o.Date != "20120115" || o.Seller != "Record Shop" {
+		t.Errorf("Ownership: got %+v", o)
+	}
+
+	if len(f.Commercial) != 1 {
+		t.Fatalf("Commercial: expected 1 frame got %d", len(f.Commercial))
+	}
+	c := f.Commercial[0]
+	if c.Price != "USD9.99/EUR8.99" {
+		t.Errorf("Commercial.Price: got '%s'", c.Price)
+	}
+	if c.ValidUntil != "20121231" {
+		t.Errorf("Commercial.ValidUntil: got '%s'", c.ValidUntil)
+	}
+	if c.ContactURL != "http://shop.example" {
+		t.Errorf("Commercial.ContactURL: got '%s'", c.ContactURL)
+	}
+	if c.ReceivedAs != 1 {
+		t.Errorf("Commercial.ReceivedAs: got %d", c.ReceivedAs)
+	}
+	if c.Seller != "Shop" || c.Description != "A fine album" {
+		t.Errorf("Commercial seller/description: got '%s'/'%s'", c.Seller, c.Description)
+	}
+	if c.LogoMIMEType != "image/png" || string(c.Logo) != "LOGO" {
+		t.Errorf("Commercial logo: got '%s' %q", c.LogoMIMEType, c.Logo)
+	}
+}
+
+func TestBOMOnlyString(t *testing.T) {
+	for _, bom := range []string{"\xff\xfe", "\xfe\xff"} {
+		tag := buildID3v2Tag(3,
+			buildID3v2Frame(3, "TIT2", []byte("\x01"+bom)),
+			buildID3v2Frame(3, "TPE1", []byte("\x00Artist")))
+
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Title != "" {
+			t.Errorf("Title: expected '' got '%s'", f.Title)
+		}
+		if f.Artist != "Artist" {
+			t.Errorf("Artist: expected 'Artist' got '%s'", f.Artist)
+		}
+	}
+}
+
+func TestUTF16NullPadding(t *testing.T) {
+	titles := []string{
+		"\x01\xff\xfeH\x00i\x00\x00\x00\x00\x00\x00",
+		"\x01\xff\xfeH\x00i\x00\x00\x00j\x00u\x00n\x00k\x00",
+		"\x01\xfe\xff\x00H\x00i\x00\x00\x00",
+	}
+	for _, title := range titles {
+		tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte(title)))
+
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Title != "Hi" {
+			t.Errorf("Title %q: expected 'Hi' got %q", title, f.Title)
+		}
+	}
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x09Caf\xe9")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Café" {
+		t.Errorf("Title: expected 'Café' got %q", f.Title)
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	for _, version := range []int{2, 3, 4} {
+		id := "TIT2"
+		if version == 2 {
+			id = "TT2"
+		}
+		frame := buildID3v2Frame(version, id, []byte("\x00Title"))
+		if version != 2 {
+			frame[len(id)+5] = 0x40
+		}
+
+		tag, flags, data, err := ReadFrame(bufio.NewReader(bytes.NewReader(frame)), version)
+		if err != nil {
+			t.Fatalf("v2.%d: %s", version, err)
+		}
+		if tag != id {
+			t.Errorf("v2.%d: expected id %s got %s", version, id, tag)
+		}
+		if version != 2 && flags != [2]byte{0, 0x40} {
+			t.Errorf("v2.%d: expected flags [0 0x40] got %v", version, flags)
+		}
+		if string(data) != "\x00Title" {
+			t.Errorf("v2.%d: expected data %q got %q", version, "\x00Title", data)
+		}
+	}
+
+	_, _, _, err := ReadFrame(bufio.NewReader(bytes.NewReader([]byte("TIT2\x00\x00"))), 3)
+	if err == nil {
+		t.Error("expected error for truncated frame")
+	}
+}
+
+func TestHugeFrameSize(t *testing.T) {
+	// a TIT2 frame declaring the largest size each version allows, within
+	// a 30 byte input
+	frames := map[int][]byte{
+		3: []byte("TIT2\xff\xff\xff\xff\x00\x00\x00Title"),
+		4: []byte("TIT2\x7f\x7f\x7f\x7f\x00\x00\x00Title"),
+	}
+	for version, frame := range frames {
+		data := append([]byte{'I', 'D', '3', byte(version), 0, 0}, syncSafe(len(frame))...)
+		data = append(data, frame...)
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		_, _, _, err := ReadFrame(bufio.NewReader(bytes.NewReader(frame)), version)
+		runtime.ReadMemStats(&after)
+		if err == nil {
+			t.Errorf("v2.%d: expected error for a frame larger than its input", version)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("v2.%d: %d bytes allocated reading a %d byte frame", version, allocated, len(frame))
+		}
+
+		if _, err := Read(bytes.NewReader(data)); err == nil {
+			t.Errorf("v2.%d: Read: expected error for a frame larger than the tag", version)
+		}
+	}
+}
+
+func TestFinalFrameAtEOF(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Artist")))
+
+	// Hide Seek so that the tag is only read from the front of the stream.
+	f, err := Read(struct{ io.Reader }{bytes.NewReader(tag)})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Artist != "Artist" {
+		t.Errorf("Artist: expected 'Artist' got '%s'", f.Artist)
+	}
+
+	// An error other than EOF between frames must not look like the end.
+	transient := errors.New("transient")
+	r := io.MultiReader(bytes.NewReader(tag[:len(tag)-17]), iotest.ErrReader(transient))
+	if _, err := Read(r); err == nil {
+		t.Error("expected error from interrupted stream")
+	}
+}
+
+func TestOneByteReader(t *testing.T) {
+	title := "A Title Longer Than The Buffer"
+	frame := buildID3v2Frame(3, "TIT2", []byte("\x00"+title))
+	_, _, data, err := ReadFrame(bufio.NewReaderSize(iotest.OneByteReader(bytes.NewReader(frame)), 16), 3)
+	if err != nil {
+		t.Fatalf("ReadFrame: %s", err)
+	}
+	if string(data[1:]) != title {
+		t.Errorf("expected %q got %q", title, data[1:])
+	}
+
+	tag := buildID3v2Tag(3, frame, buildID3v2Frame(3, "TPE1", []byte("\x00Artist")))
+	f, err := Read(iotest.OneByteReader(bytes.NewReader(tag)))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != title || f.Artist != "Artist" {
+		t.Errorf("expected %q, 'Artist' got %q, %q", title, f.Title, f.Artist)
+	}
+}
+
+func TestStrictGenres(t *testing.T) {
+	tests := []struct {
+		code   string
+		strict bool
+		genre  string
+	}{
+		{"(79)", true, "Hard Rock"},
+		{"(80)", true, "Unknown"},
+		{"(80)", false, "Folk"},
+		{"(191)", false, "Psybient"},
+		{"(192)", false, "Unknown"},
+	}
+	for _, test := range tests {
+		tag := buildID3v2Tag(3, buildID3v2Frame(3, "TCON", []byte("\x00"+test.code)))
+		f, err := ReadWithOptions(bytes.NewReader(tag), Options{StrictGenres: test.strict})
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Genre != test.genre {
+			t.Errorf("%s strict=%t: expected '%s' got '%s'", test.code, test.strict, test.genre, f.Genre)
+		}
+	}
+}
+
+func TestMood(t *testing.T) {
+	tag := buildID3v2Tag(4, buildID3v2Frame(4, "TMOO", []byte("\x03Energetic")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Mood != "Energetic" {
+		t.Errorf("Mood: expected 'Energetic' got '%s'", f.Mood)
+	}
+
+	// TMOO doesn't exist before ID3v2.4.
+	tag = buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "TMOO", []byte("\x00Calm")))
+	f, err = Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Mood != "" {
+		t.Errorf("Mood: expected '' for v2.3 got '%s'", f.Mood)
+	}
+}
+
+func TestPodcastFrames(t *testing.T) {
+	expected := SimpleTags{
+		PodcastID:          "http://example.com/ep1",
+		PodcastFeed:        "http://example.com/feed.xml",
+		PodcastDescription: "The first episode.",
+		Keywords:           "go,audio",
+	}
+	// iTunes writes the podcast frames in ID3v2.3 tags
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TGID", []byte("\x00"+expected.PodcastID)),
+		buildID3v2Frame(3, "WFED", []byte("\x00"+expected.PodcastFeed)),
+		buildID3v2Frame(3, "TDES", []byte("\x00"+expected.PodcastDescription)),
+		buildID3v2Frame(3, "TKWD", []byte("\x00"+expected.Keywords)))
+
+	for _, tag := range [][]byte{tag, encodeID3v2Tag(&expected, WriteOptions{Version: 4})} {
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.PodcastID != expected.PodcastID || f.PodcastFeed != expected.PodcastFeed ||
+			f.PodcastDescription != expected.PodcastDescription || f.Keywords != expected.Keywords {
+			t.Errorf("expected %q, %q, %q, %q got %q, %q, %q, %q",
+				expected.PodcastID, expected.PodcastFeed, expected.PodcastDescription, expected.Keywords,
+				f.PodcastID, f.PodcastFeed, f.PodcastDescription, f.Keywords)
+		}
+	}
+}
+
+func TestHasID3v2(t *testing.T) {
+	tag := buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Title")))
+	version, size, ok := HasID3v2(tag[:10])
+	if !ok || version != 4 || size != len(tag)-10 {
+		t.Errorf("expected v2.4 of size %d got %d, %d, %t", len(tag)-10, version, size, ok)
+	}
+
+	version, size, ok = HasID3v2([]byte{'I', 'D', '3', 3, 0, 0, 0, 0, 2, 1})
+	if !ok || version != 3 || size != 257 {
+		t.Errorf("expected v2.3 of size 257 got %d, %d, %t", version, size, ok)
+	}
+
+	invalid := [][]byte{
+		nil,
+		[]byte("ID3"),
+		[]byte("\xff\xfb\x90\x00\x00\x00\x00\x00\x00\x00"),
+		{'I', 'D', '3', 9, 0, 0, 0, 0, 0, 0},
+	}
+	for _, data := range invalid {
+		if _, _, ok := HasID3v2(data); ok {
+			t.Errorf("%q: unexpected ID3v2 header", data)
+		}
+	}
+}
+
+func TestFrameErrorContext(t *testing.T) {
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "COMM", []byte("\x00e")))
+
+	// Read skips the frame with a warning while ReadStrict fails on it
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || len(f.Warnings) != 1 || !strings.HasPrefix(f.Warnings[0], "frame COMM at offset 26 skipped: ") {
+		t.Errorf("expected 'Title' and a warning with frame context got %q, %q", f.Title, f.Warnings)
+	}
+	_, err = ReadStrict(bytes.NewReader(tag))
+	if err == nil || !strings.Contains(err.Error(), "frame COMM at offset 26: ") {
+		t.Errorf("ReadStrict: expected frame context in error got: %v", err)
+	}
+}
+
+func TestWrongSizeCodec(t *testing.T) {
+	album := []byte("\x00" + strings.Repeat("Long Album Name ", 12))
+
+	// A v2.4 tag with v2.3 frame sizes and a v2.3 tag with v2.4 frame sizes.
+	tags := [][]byte{
+		buildID3v2Tag(4,
+			buildID3v2Frame(3, "TALB", album),
+			buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+			buildID3v2Frame(3, "TPE1", []byte("\x00Artist"))),
+		buildID3v2Tag(3,
+			buildID3v2Frame(4, "TALB", album),
+			buildID3v2Frame(4, "TIT2", []byte("\x00Title")),
+			buildID3v2Frame(4, "TPE1", []byte("\x00Artist"))),
+	}
+	for _, tag := range tags {
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("v2.%d: %s", tag[3], err)
+		}
+		if f.Title != "Title" || f.Artist != "Artist" {
+			t.Errorf("v2.%d: expected 'Title'/'Artist' got '%s'/'%s'", tag[3], f.Title, f.Artist)
+		}
+		if f.Album != string(album[1:]) {
+			t.Errorf("v2.%d: expected album '%s' got '%s'", tag[3], album[1:], f.Album)
+		}
+	}
+}
+
+func TestCredits(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TMCL", []byte("\x03guitar\x00Jimi\x00bass\x00Noel")),
+		buildID3v2Frame(4, "TIPL", []byte("\x01\xff\xfep\x00r\x00o\x00d\x00\x00\x00\xff\xfeC\x00h\x00a\x00s\x00\x00\x00")))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := []Credit{
+		{"guitar", "Jimi"},
+		{"bass", "Noel"},
+		{"prod", "Chas"},
+	}
+	if len(f.Credits) != len(expected) {
+		t.Fatalf("Credits: expected %+v got %+v", expected, f.Credits)
+	}
+	for i, c := range expected {
+		if f.Credits[i] != c {
+			t.Errorf("Credits[%d]: expected %+v got %+v", i, c, f.Credits[i])
+		}
+	}
+}
+
+func TestTagUnsynchronization(t *testing.T) {
+	// the UTF-16 BOM and the 255 byte frame size both hold an 0xFF that
+	// unsynchronization follows with 0x00
+	title := buildID3v2Frame(3, "TIT2", []byte("\x01\xff\xfeT\x00i\x00"))
+	album := buildID3v2Frame(3, "TALB", append([]byte{0}, bytes.Repeat([]byte("A"), 254)...))
+	plain := buildID3v2Tag(3, title, album)
+	body := applyUnsynchronization(plain[10:])
+	if bytes.Equal(body, plain[10:]) {
+		t.Fatal("expected unsynchronization to change the tag")
+	}
+	v23 := append([]byte{'I', 'D', '3', 3, 0, 1 << 7}, syncSafe(len(body))...)
+	v23 = append(v23, body...)
+
+	// ID3v2.4 applies the tag's flag to each frame rather than the tag
+	frame := buildID3v2Frame(4, "TIT2", applyUnsynchronization([]byte("\x01\xff\xfeT\x00i\x00")))
+	v24 := buildID3v2Tag(4, frame)
+	v24[5] = 1 << 7
+
+	for _, tag := range [][]byte{v23, v24} {
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("v2.%d: Read: %s", tag[3], err)
+		}
+		if !f.Header.Unsynchronization || f.Title != "Ti" {
+			t.Errorf("v2.%d: expected an unsynchronized tag titled 'Ti' got %t %q", tag[3], f.Header.Unsynchronization, f.Title)
+		}
+	}
+	f, _ := Read(bytes.NewReader(v23))
+	if f.Album != strings.Repeat("A", 254) {
+		t.Errorf("v2.3: Album: got %q", f.Album)
+	}
+
+	// tags written with WriteOptions.Unsynchronize read back
+	tags := &SimpleTags{Title: "ÿé", Artist: "Björk 日本"}
+	f, err := Read(bytes.NewReader(encodeID3v2Tag(tags, WriteOptions{Version: 3, Unsynchronize: true})))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != tags.Title || f.Artist != tags.Artist {
+		t.Errorf("expected %q, %q got %q, %q", tags.Title, tags.Artist, f.Title, f.Artist)
+	}
+}
+
+func TestExtendedHeader(t *testing.T) {
+	title := buildID3v2Frame(4, "TIT2", []byte("\x03Title"))
+	// size 12, one flag byte with the CRC flag set and the 5 byte CRC
+	ext := []byte("\x00\x00\x00\x0c\x01\x20\x05\x01\x11\x52\x57\x4d")
+	v24 := buildID3v2Tag(4, ext, title)
+	v24[5] = 1 << 6
+
+	title = buildID3v2Frame(3, "TIT2", []byte("\x00Title"))
+	// size 10 excluding itself, the CRC flag, no padding and the CRC
+	ext = []byte("\x00\x00\x00\x0a\x80\x00\x00\x00\x00\x00\x12\x34\xab\xcd")
+	v23 := buildID3v2Tag(3, ext, title)
+	v23[5] = 1 << 6
+
+	for _, tag := range [][]byte{v24, v23} {
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("v2.%d: Read: %s", tag[3], err)
+		}
+		if f.Title != "Title" {
+			t.Errorf("v2.%d: expected 'Title' got %q", tag[3], f.Title)
+		}
+		expected := ExtendedHeader{HasCRC: true, CRC: 0x1234abcd}
+		if f.ExtendedHeader == nil || *f.ExtendedHeader != expected {
+			t.Errorf("v2.%d: expected %+v got %+v", tag[3], expected, f.ExtendedHeader)
+		}
+	}
+
+	// the update flag has no data and restrictions have a single byte
+	ext = []byte("\x00\x00\x00\x09\x01\x50\x00\x01\x8c")
+	tag := buildID3v2Tag(4, ext, buildID3v2Frame(4, "TIT2", []byte("\x03Title")))
+	tag[5] = 1 << 6
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := ExtendedHeader{Update: true, HasRestrictions: true, Restrictions: 0x8c}
+	if f.Title != "Title" || f.ExtendedHeader == nil || *f.ExtendedHeader != expected {
+		t.Errorf("expected %+v got %q, %+v", expected, f.Title, f.ExtendedHeader)
+	}
+}
+
+func TestFrameUnsynchronization(t *testing.T) {
+	album := buildID3v2Frame(4, "TALB", []byte("\x00\xff\x00Album"))
+	album[9] = id3v24FrameUnsynchronized
+	// a frame that isn't unsynchronized keeps its 0xFF 0x00 pairs
+	title := buildID3v2Frame(4, "TIT2", []byte("\x00Title\xff\x00"))
+	tag := buildID3v2Tag(4, album, title)
+
+	header, frames, err := ReadAllFrames(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("ReadAllFrames: %s", err)
+	}
+	if header.Unsynchronization {
+		t.Errorf("expected the header not to be unsynchronized")
+	}
+	if len(frames) != 2 {
+		t.Fatalf("expected 2 frames got %d", len(frames))
+	}
+	if !frames[0].Unsynchronized || string(frames[0].Data) != "\x00\xffAlbum" {
+		t.Errorf("TALB: expected unsynchronized %q got %t %q", "\x00\xffAlbum", frames[0].Unsynchronized, frames[0].Data)
+	}
+	if frames[1].Unsynchronized || string(frames[1].Data) != "\x00Title\xff\x00" {
+		t.Errorf("TIT2: expected %q got %t %q", "\x00Title\xff\x00", frames[1].Unsynchronized, frames[1].Data)
+	}
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Album != "ÿAlbum" {
+		t.Errorf("Album: expected 'ÿAlbum' got '%s'", f.Album)
+	}
+}
+
+func TestTagSize(t *testing.T) {
+	tag := buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x00Title")))
+	tests := []struct {
+		data     []byte
+		expected int64
+	}{
+		{append(append([]byte{}, tag...), 0xff, 0xfb), int64(len(tag))},
+		{addID3v2Footer(append([]byte{}, tag...)), int64(len(tag) + 10)},
+		{[]byte{0xff, 0xfb, 0x90, 0x00, 0, 0, 0, 0, 0, 0}, 0},
+		{[]byte("ID3"), 0},
+	}
+	for i, test := range tests {
+		// only the header may be read so the reader needn't seek
+		size, err := TagSize(iotest.OneByteReader(bytes.NewReader(test.data)))
+		if err != nil {
+			t.Errorf("%d: TagSize: %s", i, err)
+		}
+		if size != test.expected {
+			t.Errorf("%d: expected %d got %d", i, test.expected, size)
+		}
+	}
+}
+
+func TestSeekFrame(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	first := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Artist")),
+		buildID3v2Frame(4, "SEEK", []byte{0, 0, 1, 0}))
+	update := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03New Title")),
+		buildID3v2Frame(4, "TALB", []byte("\x03Album")))
+	file := append(append(append(first, audio...), update...), audio...)
+
+	f, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.SeekOffset != int64(len(audio)) {
+		t.Errorf("SeekOffset: expected %d got %d", len(audio), f.SeekOffset)
+	}
+	if f.Title != "New Title" || f.Artist != "Artist" || f.Album != "Album" {
+		t.Errorf("expected the update to be merged got %q, %q, %q", f.Title, f.Artist, f.Album)
+	}
+
+	// a SEEK frame pointing nowhere leaves the tag as it is
+	f, err = Read(bytes.NewReader(append(first, audio[:32]...)))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Album != "" {
+		t.Errorf("expected only the first tag got %q, %q", f.Title, f.Album)
+	}
+}
+
+func TestGenreRaw(t *testing.T) {
+	tests := []struct {
+		tcon  string
+		genre string
+	}{
+		{"(17)", "Rock"},
+		{"17", "Rock"},
+		{"Rock", "Rock"},
+		{"(17)Classic Rock", "Rock"},
+		{"Rock/(17)", "Rock"},
+		{"(17)/Rock", "Rock"},
+		{"Indie Rock/(17)", "Indie Rock"},
+		{"Drum & Bass (127)", "Drum & Bass"},
+		// only a genre code is stripped from the end of a name
+		{"Dance (Remix)", "Dance (Remix)"},
+		{"Hits (1999)", "Hits (1999)"},
+		{"Rock (-1)", "Rock (-1)"},
+		// blank genres and code 255 mean there's no genre
+		{"", ""},
+		{"  ", ""},
+		{"(255)", ""},
+		{"255", ""},
+	}
+	for _, test := range tests {
+		tag := buildID3v2Tag(3,
+			buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+			buildID3v2Frame(3, "TCON", []byte("\x00"+test.tcon)))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%q: Read: %s", test.tcon, err)
+		}
+		if f.Genre != test.genre || f.GenreRaw != test.tcon {
+			t.Errorf("%q: expected %q, %q got %q, %q", test.tcon, test.genre, test.tcon, f.Genre, f.GenreRaw)
+		}
+		if test.genre == "" && len(f.Genres) != 0 {
+			t.Errorf("%q: expected no genres got %q", test.tcon, f.Genres)
+		}
+	}
+
+	// a TCON frame of size 0 lacks even the encoding byte, as may other
+	// text frames
+	for _, version := range []int{2, 3, 4} {
+		ids := []string{"TIT2", "TCON", "TALB", "TBPM", "WFED"}
+		if version == 2 {
+			ids = []string{"TT2", "TCO", "TAL", "TBP"}
+		} else if version == 4 {
+			ids = append(ids, "TDRC")
+		}
+		frames := [][]byte{}
+		for _, id := range ids {
+			frames = append(frames, buildID3v2Frame(version, id, nil))
+		}
+		f, err := Read(bytes.NewReader(buildID3v2Tag(version, frames...)))
+		if err != nil {
+			t.Fatalf("v2.%d: empty frames: Read: %s", version, err)
+		}
+		if f.Genre != "" || f.Title != "" || len(f.Genres) != 0 {
+			t.Errorf("v2.%d: empty frames: expected no values got %q, %q, %q", version, f.Genre, f.Title, f.Genres)
+		}
+	}
+}
+
+func TestEncryptedAudio(t *testing.T) {
+	aenc := []byte("http://drm.example.com\x00\x00\x10\x00\x20key")
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "AENC", aenc))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if !f.Encrypted || !bytes.Equal(f.RawFrames["AENC"], aenc) {
+		t.Errorf("expected encrypted with AENC %q got %t, %q", aenc, f.Encrypted, f.RawFrames["AENC"])
+	}
+
+	f, err = Read(bytes.NewReader(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")))))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Encrypted || f.RawFrames != nil {
+		t.Errorf("expected no encryption got %t, %q", f.Encrypted, f.RawFrames)
+	}
+}
+
+func TestLenientFrameFlags(t *testing.T) {
+	// the final frame's flags are missing
+	artist := buildID3v2Frame(3, "TPE1", []byte("\x00Artist"))
+	artist = append(artist[:8], artist[10:]...)
+	tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")), artist)
+
+	if _, err := Read(bytes.NewReader(tag)); err == nil {
+		t.Errorf("expected an error without LenientFrameFlags")
+	}
+
+	f, err := ReadWithOptions(bytes.NewReader(tag), Options{LenientFrameFlags: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Artist != "Artist" {
+		t.Errorf("expected 'Title', 'Artist' got %q, %q", f.Title, f.Artist)
+	}
+
+	// the ID of a flagless frame is matched regardless of case too
+	copy(tag[len(tag)-len(artist):], "tpe1")
+	f, err = ReadWithOptions(bytes.NewReader(tag), Options{LenientFrameFlags: true, CaseInsensitiveFrames: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Artist != "Artist" {
+		t.Errorf("lowercase: expected 'Title', 'Artist' got %q, %q", f.Title, f.Artist)
+	}
+}
+
+func TestLenientEncoding(t *testing.T) {
+	// declared as UTF-16 but holding UTF-8 behind a UTF-8 BOM
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x01\xef\xbb\xbfCaf\xc3\xa9")),
+		buildID3v2Frame(3, "TPE1", []byte("\x01\xff\xfeA\x00")))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title == "Café" {
+		t.Errorf("expected the title to be undecodable without LenientEncoding")
+	}
+
+	f, err = ReadWithOptions(bytes.NewReader(tag), Options{LenientEncoding: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Café" || f.Artist != "A" {
+		t.Errorf("expected 'Café', 'A' got %q, %q", f.Title, f.Artist)
+	}
+}
+
+func TestCaseInsensitiveFrames(t *testing.T) {
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "tit2", []byte("\x00Title")),
+		buildID3v2Frame(3, "Tpe1", []byte("\x00Artist")))
+
+	if _, err := Read(bytes.NewReader(tag)); err != ErrNoTags {
+		t.Errorf("expected ErrNoTags by default got %v", err)
+	}
+
+	f, err := ReadWithOptions(bytes.NewReader(tag), Options{CaseInsensitiveFrames: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Artist != "Artist" {
+		t.Errorf("expected 'Title', 'Artist' got %q, %q", f.Title, f.Artist)
+	}
+}
+
+func TestMalformedIdentifier(t *testing.T) {
+	tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")))
+
+	// a UTF-8 BOM before the header is always skipped
+	f, err := Read(bytes.NewReader(append([]byte("\xef\xbb\xbf"), tag...)))
+	if err != nil {
+		t.Fatalf("BOM: Read: %s", err)
+	}
+	if f.Title != "Title" {
+		t.Errorf("BOM: expected 'Title' got %q", f.Title)
+	}
+
+	lower := append([]byte("id3"), tag[3:]...)
+	if _, err := Read(bytes.NewReader(lower)); err == nil {
+		t.Errorf("expected an error for 'id3' by default")
+	}
+	for _, data := range [][]byte{lower, append([]byte("\xef\xbb\xbf"), lower...)} {
+		f, err := ReadWithOptions(bytes.NewReader(data), Options{CaseInsensitiveFrames: true})
+		if err != nil {
+			t.Fatalf("%q: Read: %s", data[:6], err)
+		}
+		if f.Title != "Title" {
+			t.Errorf("%q: expected 'Title' got %q", data[:6], f.Title)
+		}
+	}
+}
+
+func TestRecordedAt(t *testing.T) {
+	tests := []struct {
+		tdrc      string
+		at        time.Time
+		precision TimestampPrecision
+	}{
+		{"2008", time.Date(2008, time.January, 1, 0, 0, 0, 0, time.UTC), PrecisionYear},
+		{"2008-03", time.Date(2008, time.March, 1, 0, 0, 0, 0, time.UTC), PrecisionMonth},
+		{"2008-03-15", time.Date(2008, time.March, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2008-03-15T21", time.Date(2008, time.March, 15, 21, 0, 0, 0, time.UTC), PrecisionHour},
+		{"2008-03-15T21:30", time.Date(2008, time.March, 15, 21, 30, 0, 0, time.UTC), PrecisionMinute},
+		{"2008-03-15T21:30:45", time.Date(2008, time.March, 15, 21, 30, 45, 0, time.UTC), PrecisionSecond},
+		{"March 2008", time.Time{}, 0},
+	}
+	for _, test := range tests {
+		tag := buildID3v2Tag(4, buildID3v2Frame(4, "TDRC", []byte("\x03"+test.tdrc)))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%s: Read: %s", test.tdrc, err)
+		}
+		if f.Year != test.tdrc {
+			t.Errorf("%s: expected Year to be kept got '%s'", test.tdrc, f.Year)
+		}
+		if !f.RecordedAt.Equal(test.at) || f.RecordedAt.Location() != time.UTC || f.RecordedPrecision != test.precision {
+			t.Errorf("%s: expected %s (%d) got %s (%d)", test.tdrc, test.at, test.precision, f.RecordedAt, f.RecordedPrecision)
+		}
+	}
+}
+
+func TestReleaseTime(t *testing.T) {
+	tests := []struct {
+		tdrl      string
+		at        time.Time
+		precision TimestampPrecision
+	}{
+		{"2013", time.Date(2013, time.January, 1, 0, 0, 0, 0, time.UTC), PrecisionYear},
+		{"2013-06-15", time.Date(2013, time.June, 15, 0, 0, 0, 0, time.UTC), PrecisionDay},
+		{"2013-06-15T09:30:12", time.Date(2013, time.June, 15, 9, 30, 12, 0, time.UTC), PrecisionSecond},
+	}
+	for _, test := range tests {
+		tag := buildID3v2Tag(4, buildID3v2Frame(4, "TDRL", []byte("\x03"+test.tdrl)))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%s: Read: %s", test.tdrl, err)
+		}
+		if f.ReleaseTime != test.tdrl || !f.ReleasedAt.Equal(test.at) || f.ReleasedPrecision != test.precision {
+			t.Errorf("%s: expected %s (%d) got '%s', %s (%d)", test.tdrl, test.at, test.precision, f.ReleaseTime, f.ReleasedAt, f.ReleasedPrecision)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteTag(&buf, f, WriteOptions{}); err != nil {
+			t.Fatalf("%s: WriteTag: %s", test.tdrl, err)
+		}
+		if f, err = Read(&buf); err != nil {
+			t.Fatalf("%s: Read: %s", test.tdrl, err)
+		}
+		if f.ReleaseTime != test.tdrl {
+			t.Errorf("%s: expected the release time to round trip got '%s'", test.tdrl, f.ReleaseTime)
+		}
+	}
+}
+
+func TestReadPastHeaderSize(t *testing.T) {
+	title := buildID3v2Frame(3, "TIT2", []byte("\x00Title"))
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	tag := buildID3v2Tag(3, title, buildID3v2Frame(3, "TPE1", []byte("\x00Artist")))
+	copy(tag[6:], syncSafe(len(title)))
+	file := append(tag, audio...)
+
+	f, err := Read(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Artist != "" || len(f.Warnings) != 0 {
+		t.Errorf("expected only the declared frames got %q, %q, %q", f.Title, f.Artist, f.Warnings)
+	}
+
+	f, err = ReadWithOptions(bytes.NewReader(file), Options{ReadPastHeaderSize: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Artist != "Artist" {
+		t.Errorf("expected 'Title', 'Artist' got %q, %q", f.Title, f.Artist)
+	}
+	if len(f.Warnings) != 1 || !strings.Contains(f.Warnings[0], "tag size 16 is smaller than its 33 bytes") {
+		t.Errorf("expected a warning about the tag size got %q", f.Warnings)
+	}
+
+	// a correct size gives no warning
+	f, err = ReadWithOptions(bytes.NewReader(append(buildID3v2Tag(3, title), audio...)), Options{ReadPastHeaderSize: true})
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if len(f.Warnings) != 0 {
+		t.Errorf("expected no warnings got %q", f.Warnings)
+	}
+
+	// In an unsynchronized tag the declared size may end between a 0xFF
+	// and the 0x00 escaping it. "ÿà" is 0xFF 0xE0 in ISO-8859-1.
+	body := applyUnsynchronization(append(
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title \xff\xe0")),
+		buildID3v2Frame(3, "TPE1", []byte("\x00Artist"))...))
+	escape := bytes.Index(body, []byte{0xff, 0x00})
+	tag = append([]byte{'I', 'D', '3', 3, 0, 1 << 7}, syncSafe(escape+1)...)
+	f, err = ReadWithOptions(bytes.NewReader(append(append(tag, body...), audio...)), Options{ReadPastHeaderSize: true})
+	if err != nil {
+		t.Fatalf("unsynchronized: Read: %s", err)
+	}
+	if f.Title != "Title ÿà" || f.Artist != "Artist" {
+		t.Errorf("unsynchronized: expected 'Title ÿà', 'Artist' got %q, %q", f.Title, f.Artist)
+	}
+}
+
+func TestGenres(t *testing.T) {
+	tests := []struct {
+		version int
+		tcon    string
+		genres  []string
+	}{
+		{3, "(0)(2)Eurodisco", []string{"Blues", "Country", "Eurodisco"}},
+		{3, "(17)", []string{"Rock"}},
+		{3, "(17)Rock", []string{"Rock"}},
+		{3, "(RX)(CR)", []string{"Remix", "Cover"}},
+		{3, "(4)((I think...)", []string{"Disco", "(I think...)"}},
+		{3, "Rock/(17)", []string{"Rock"}},
+		{4, "17\x00Eurodisco\x00RX", []string{"Rock", "Eurodisco", "Remix"}},
+	}
+	for _, test := range tests {
+		encoding := "\x00"
+		if test.version == 4 {
+			encoding = "\x03"
+		}
+		tag := buildID3v2Tag(test.version, buildID3v2Frame(test.version, "TCON", []byte(encoding+test.tcon)))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%q: Read: %s", test.tcon, err)
+		}
+		if !reflect.DeepEqual(f.Genres, test.genres) {
+			t.Errorf("%q: expected %q got %q", test.tcon, test.genres, f.Genres)
+		}
+	}
+
+	// an ID3v1 genre fills in Genres too
+	v1 := buildID3v1Tag("Title")
+	v1[127] = 17
+	f, err := Read(bytes.NewReader(append(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title"))), v1...)))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if !reflect.DeepEqual(f.Genres, []string{"Rock"}) {
+		t.Errorf("expected Genres to hold \"Rock\" got %q", f.Genres)
+	}
+
+	// but the ID3v1 code for no genre doesn't
+	v1[127] = 255
+	if f, err = Read(bytes.NewReader(v1)); err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Genre != "" || len(f.Genres) != 0 {
+		t.Errorf("genre 255: expected no genre got %q, %q", f.Genre, f.Genres)
+	}
+}
+
+func TestUTF16BE(t *testing.T) {
+	tests := map[string]string{
+		"\x02\x00B\x00j\x00\xf6\x00r\x00k":             "Björk",
+		"\x02\x00B\x00j\x00\xf6\x00r\x00k\x00":         "Björk",
+		"\x02\x00B\x00j\x00\xf6\x00r\x00k\x00\x00\x00": "Björk",
+		"\x02\xd8\x34\xdd\x1e":                         "𝄞",
+		"\x02":                                         "",
+	}
+	for data, expected := range tests {
+		tag := buildID3v2Tag(4,
+			buildID3v2Frame(4, "TIT2", []byte(data)),
+			buildID3v2Frame(4, "TPE1", []byte("\x03Artist")))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%q: Read: %s", data, err)
+		}
+		if f.Title != expected {
+			t.Errorf("%q: expected %q got %q", data, expected, f.Title)
+		}
+	}
+}
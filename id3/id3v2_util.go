@@ -17,6 +17,7 @@ package id3
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 	"unicode/utf16"
@@ -30,14 +31,33 @@ func hasID3v2Tag(reader *bufio.Reader) bool {
 	return string(data) == "ID3"
 }
 
-// Peeks at the buffer to see if there is a valid frame.
-func hasID3v2Frame(reader *bufio.Reader, frameSize int) bool {
-	data, err := reader.Peek(frameSize)
-	if err != nil {
+// Works around headers written by broken encoders: a UTF-8 byte order mark
+// before the ID3v2 header is skipped and, if lowercase is set, an "id3"
+// identifier in any case is read as "ID3". Returns the reader to parse
+// the tag from and the number of bytes skipped.
+func fixID3v2Identifier(reader *bufio.Reader, size int, lowercase bool) (*bufio.Reader, int64) {
+	var skipped int64
+	data, err := reader.Peek(6)
+	if err == nil && string(data[:3]) == "\xef\xbb\xbf" && strings.EqualFold(string(data[3:]), "ID3") {
+		reader.Discard(3)
+		skipped = 3
+	}
+
+	data, err = reader.Peek(3)
+	if err == nil && lowercase && string(data) != "ID3" && strings.EqualFold(string(data), "ID3") {
+		reader.Discard(3)
+		reader = bufio.NewReaderSize(io.MultiReader(strings.NewReader("ID3"), reader), size)
+	}
+	return reader, skipped
+}
+
+// Checks whether data starts with a valid frame ID.
+func hasID3v2Frame(data []byte, frameSize int) bool {
+	if len(data) < frameSize {
 		return false
 	}
 
-	for _, c := range data {
+	for _, c := range data[:frameSize] {
 		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
 			return false
 		}
@@ -46,18 +66,26 @@ func hasID3v2Frame(reader *bufio.Reader, frameSize int) bool {
 }
 
 func parseID3v2Header(reader *bufio.Reader) (*ID3v2Header, error) {
-	h := new(ID3v2Header)
 	data, err := readBytes(reader, 10)
 	if err != nil {
 		return nil, fmt.Errorf("parseHeader: %s", err)
 	}
+	return decodeID3v2Header(data)
+}
+
+// Decodes the 10 byte ID3v2 header at the start of data.
+func decodeID3v2Header(data []byte) (*ID3v2Header, error) {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return nil, fmt.Errorf("parseHeader: no ID3v2 tag")
+	}
 
+	h := new(ID3v2Header)
 	h.Version = int(data[3])
 	h.MinorVersion = int(data[4])
-	h.Unsynchronization = data[5]&1<<7 != 0
-	h.Extended = data[5]&1<<6 != 0
-	h.Experimental = data[5]&1<<5 != 0
-	h.Footer = data[5]&1<<4 != 0
+	h.Unsynchronization = data[5]&(1<<7) != 0
+	h.Extended = data[5]&(1<<6) != 0
+	h.Experimental = data[5]&(1<<5) != 0
+	h.Footer = data[5]&(1<<4) != 0
 	h.Size = parseID3v2Size(data[6:])
 
 	return h, nil
@@ -78,14 +106,29 @@ func parseID3v2Size(data []byte) int32 {
 	return size
 }
 
+// Inverse of parseID3v2Size: encodes size as 4 bytes of 7 bits each.
+func encodeID3v2Size(size int32) []byte {
+	return []byte{
+		byte(size>>21) & 0x7f,
+		byte(size>>14) & 0x7f,
+		byte(size>>7) & 0x7f,
+		byte(size) & 0x7f,
+	}
+}
+
 // Parses a string from frame data. The first byte represents the encoding:
-//   0x01  ISO-8859-1
-//   0x02  UTF-16 w/ BOM
-//   0x03  UTF-16BE w/o BOM
-//   0x04  UTF-8
+//   0x00  ISO-8859-1
+//   0x01  UTF-16 w/ BOM
+//   0x02  UTF-16BE w/o BOM
+//   0x03  UTF-8
+// Any other encoding byte is taken to be ISO-8859-1.
 //
 // Refer to section 4 of http://id3.org/id3v2.4.0-structure
 func parseID3v2String(data []byte) (string, error) {
+	// a frame without even an encoding byte holds nothing
+	if len(data) == 0 {
+		return "", nil
+	}
 	var s string
 	switch data[0] {
 	case 0: // ISO-8859-1 text.
@@ -96,39 +139,56 @@ func parseID3v2String(data []byte) (string, error) {
 		if err != nil {
 			return "", err
 		}
-		s = string(utf16.Decode(utf))
+		s = cutAtNull(string(utf16.Decode(utf)))
 		break
 	case 2: // UTF-16BE without BOM.
-		return "", fmt.Errorf("Unsupported text encoding UTF-16BE.")
+		s = cutAtNull(string(utf16.Decode(toUTF16BE(data[1:]))))
+		break
 	case 3: // UTF-8 text.
 		s = string(data[1:])
 		break
 	default:
-		// No encoding, assume ISO-8859-1 text.
-		s = ISO8859_1ToUTF8(data)
+		// Unknown encoding, assume ISO-8859-1 text.
+		s = ISO8859_1ToUTF8(data[1:])
 	}
 	return strings.TrimRight(s, "\u0000"), nil
 }
 
-func readID3v2String(reader *bufio.Reader, c int) (string, error) {
-	b, err := readBytes(reader, c)
-	if err != nil {
-		return "", err
+// Truncates s at its first U+0000. Decoded UTF-16 often carries null padding
+// after the terminator that TrimRight alone can't remove.
+func cutAtNull(s string) string {
+	if i := strings.IndexRune(s, 0); i >= 0 {
+		return s[:i]
 	}
-	return parseID3v2String(b)
+	return s
+}
+
+// Relabels frame data declared as UTF-16 but holding UTF-8 behind a UTF-8
+// byte order mark, as written by some encoders, as UTF-8 without the mark.
+// Other data is returned as is.
+func fixUTF8BOM(data []byte) []byte {
+	if len(data) < 4 || data[0] != 1 || string(data[1:4]) != "\xef\xbb\xbf" {
+		return data
+	}
+	return append([]byte{3}, data[4:]...)
 }
 
 // ID3v2.2 and ID3v2.3 use "(NN)" where as ID3v2.4 simply uses "NN" when
 // referring to ID3v1 genres. The "(NN)" format is allowed to have trailing
 // information.
 //
-// RX and CR are shorthand for Remix and Cover, respectively.
+// RX and CR are shorthand for Remix and Cover, respectively. Codes are
+// looked up in genres.
 //
 // Refer to the following documentation:
 //   http://id3.org/id3v2-00          TCO frame
 //   http://id3.org/id3v2.3.0         TCON frame
 //   http://id3.org/id3v2.4.0-frames  TCON frame
-func convertID3v1Genre(genre string) string {
+func convertID3v1Genre(genre string, genres []string) string {
+	// A blank genre and code 255, which ID3v1 uses for none, mean no genre.
+	if strings.TrimSpace(genre) == "" {
+		return ""
+	}
 	if genre == "RX" || strings.HasPrefix(genre, "(RX)") {
 		return "Remix"
 	}
@@ -139,8 +199,11 @@ func convertID3v1Genre(genre string) string {
 	// Try to parse "NN" format.
 	index, err := strconv.Atoi(genre)
 	if err == nil {
-		if index >= 0 && index < len(id3v1Genres) {
-			return id3v1Genres[index]
+		if index == 255 {
+			return ""
+		}
+		if index >= 0 && index < len(genres) {
+			return genres[index]
 		}
 		return "Unknown"
 	}
@@ -149,24 +212,80 @@ func convertID3v1Genre(genre string) string {
 	index = 0
 	_, err = fmt.Sscanf(genre, "(%d)", &index)
 	if err == nil {
-		if index >= 0 && index < len(id3v1Genres) {
-			return id3v1Genres[index]
+		if index == 255 {
+			return ""
+		}
+		if index >= 0 && index < len(genres) {
+			return genres[index]
 		}
 		return "Unknown"
 	}
 
+	// Free text followed by a code, e.g. "Rock/(17)", keeps the text. Other
+	// parentheticals such as "Hits (1999)" are part of the name.
+	if i := strings.LastIndex(genre, "("); i > 0 && strings.HasSuffix(genre, ")") {
+		if n, ok := parseNumber(genre[i+1 : len(genre)-1]); ok && n < len(genres) {
+			if text := strings.TrimRight(genre[:i], "/ "); text != "" {
+				return text
+			}
+		}
+	}
+
 	// Couldn't parse so it's likely not an ID3v1 genre.
 	return genre
 }
 
-func readID3v2Genre(reader *bufio.Reader, c int) (string, error) {
-	b, err := readBytes(reader, c)
+// Returns both the resolved genre name and the raw frame value.
+func parseID3v2Genre(data []byte, genres []string) (genre string, raw string, err error) {
+	raw, err = parseID3v2String(data)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	genre, err := parseID3v2String(b)
+	return convertID3v1Genre(raw, genres), raw, nil
+}
+
+// Parses every genre of a TCON frame. ID3v2.3 values are a list of
+// parenthesised codes, "RX" and "CR" included, optionally followed by a
+// refinement in which "((" stands for "(". ID3v2.4 frames instead hold null
+// separated values. Codes are resolved to names and repeated genres are
+// dropped.
+func parseID3v2Genres(data []byte, genres []string) ([]string, error) {
+	values, err := parseID3v2Strings(data)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	var result []string
+	add := func(genre string) {
+		for _, g := range result {
+			if strings.EqualFold(g, genre) {
+				return
+			}
+		}
+		result = append(result, genre)
+	}
+	for _, v := range values {
+		rest := v
+		for strings.HasPrefix(rest, "(") && !strings.HasPrefix(rest, "((") {
+			end := strings.Index(rest, ")")
+			if end < 0 {
+				break
+			}
+			code := rest[1:end]
+			if _, err := strconv.Atoi(code); err != nil && code != "RX" && code != "CR" {
+				break
+			}
+			if genre := convertID3v1Genre(code, genres); genre != "" {
+				add(genre)
+			}
+			rest = rest[end+1:]
+		}
+		if strings.HasPrefix(rest, "((") {
+			rest = rest[1:]
+		}
+		if genre := convertID3v1Genre(strings.TrimSpace(rest), genres); genre != "" {
+			add(genre)
+		}
 	}
-	return convertID3v1Genre(genre), nil
+	return result, nil
 }
@@ -0,0 +1,92 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SoundCheck returns the volume normalization values iTunes stores as
+// space-separated hex integers in the "iTunNORM" comment.
+func (t *SimpleTags) SoundCheck() ([]int, bool) {
+	for _, c := range t.Comments {
+		if c.Description != "iTunNORM" {
+			continue
+		}
+
+		fields := strings.Fields(c.Text)
+		if len(fields) == 0 {
+			return nil, false
+		}
+		values := make([]int, len(fields))
+		for i, f := range fields {
+			v, err := strconv.ParseUint(f, 16, 32)
+			if err != nil {
+				return nil, false
+			}
+			values[i] = int(v)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// Gapless holds the sample boundaries iTunes stores in the "iTunSMPB"
+// comment: the samples the encoder added at the start and end of the audio,
+// and the number of samples between them.
+type Gapless struct {
+	EncoderDelay   int
+	EncoderPadding int
+	SampleCount    int64
+}
+
+// GaplessInfo decodes the space-separated hex fields of the "iTunSMPB"
+// comment.
+func (t *SimpleTags) GaplessInfo() (Gapless, bool) {
+	for _, c := range t.Comments {
+		if c.Description != "iTunSMPB" {
+			continue
+		}
+
+		fields := strings.Fields(c.Text)
+		if len(fields) < 4 {
+			return Gapless{}, false
+		}
+		delay, err1 := strconv.ParseUint(fields[1], 16, 32)
+		padding, err2 := strconv.ParseUint(fields[2], 16, 32)
+		count, err3 := strconv.ParseUint(fields[3], 16, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return Gapless{}, false
+		}
+		return Gapless{int(delay), int(padding), int64(count)}, true
+	}
+	return Gapless{}, false
+}
+
+// SetGaplessInfo stores g in an "iTunSMPB" comment, replacing any existing
+// one, so that it is written with the rest of the tags.
+func (t *SimpleTags) SetGaplessInfo(g Gapless) {
+	text := fmt.Sprintf(" 00000000 %08X %08X %016X%s",
+		g.EncoderDelay, g.EncoderPadding, g.SampleCount, strings.Repeat(" 00000000", 8))
+	for i, c := range t.Comments {
+		if c.Description == "iTunSMPB" {
+			t.Comments[i].Text = text
+			return
+		}
+	}
+	t.Comments = append(t.Comments, Comment{"eng", "iTunSMPB", text})
+}
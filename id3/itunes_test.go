@@ -0,0 +1,85 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSoundCheck(t *testing.T) {
+	norm := " 00000180 0000016D 00001E5F 00001CB5 00007FE0 00007FE0 00007FFF 00007FFF 00004B7E 00004B7E"
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "COMM", []byte("\x00engiTunNORM\x00"+norm)))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	values, ok := f.SoundCheck()
+	if !ok {
+		t.Fatal("SoundCheck: expected values")
+	}
+	expected := []int{0x180, 0x16D, 0x1E5F, 0x1CB5, 0x7FE0, 0x7FE0, 0x7FFF, 0x7FFF, 0x4B7E, 0x4B7E}
+	if len(values) != len(expected) {
+		t.Fatalf("SoundCheck: expected %d values got %d", len(expected), len(values))
+	}
+	for i, v := range expected {
+		if values[i] != v {
+			t.Errorf("SoundCheck[%d]: expected %#x got %#x", i, v, values[i])
+		}
+	}
+
+	if _, ok := new(SimpleTags).SoundCheck(); ok {
+		t.Error("SoundCheck: expected no values without an iTunNORM comment")
+	}
+}
+
+func TestGaplessInfo(t *testing.T) {
+	smpb := " 00000000 00000210 000007A8 0000000000C5E7C8 00000000 00C6E3B6 00000000 00000000 00000000 00000000 00000000 00000000"
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		buildID3v2Frame(3, "COMM", []byte("\x00engiTunSMPB\x00"+smpb)))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	g, ok := f.GaplessInfo()
+	expected := Gapless{EncoderDelay: 528, EncoderPadding: 1960, SampleCount: 12969928}
+	if !ok || g != expected {
+		t.Errorf("GaplessInfo: expected %+v got %+v (%v)", expected, g, ok)
+	}
+
+	// round trip through a freshly written tag
+	tags := &SimpleTags{Title: "Title"}
+	tags.SetGaplessInfo(expected)
+	tags.SetGaplessInfo(expected)
+	if len(tags.Comments) != 1 {
+		t.Errorf("expected one iTunSMPB comment got %d", len(tags.Comments))
+	}
+	f, err = Read(bytes.NewReader(encodeID3v2Tag(tags, WriteOptions{})))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if g, ok := f.GaplessInfo(); !ok || g != expected {
+		t.Errorf("round trip: expected %+v got %+v (%v)", expected, g, ok)
+	}
+
+	if _, ok := new(SimpleTags).GaplessInfo(); ok {
+		t.Error("GaplessInfo: expected nothing without an iTunSMPB comment")
+	}
+}
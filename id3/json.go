@@ -0,0 +1,58 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import "encoding/json"
+
+// MarshalJSON encodes the fields returned by Map as a flat object, along
+// with "version" for the ID3v2 major version and, when present, "comments",
+// "usertext" and "pictures". Pictures are described without their data.
+func (t *SimpleTags) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{}
+	for k, v := range t.Map() {
+		m[k] = v
+	}
+	if t.Header != nil {
+		m["version"] = t.Header.Version
+	}
+
+	if len(t.Comments) > 0 {
+		comments := make([]map[string]string, len(t.Comments))
+		for i, c := range t.Comments {
+			comments[i] = map[string]string{
+				"language":    c.Language,
+				"description": c.Description,
+				"text":        c.Text,
+			}
+		}
+		m["comments"] = comments
+	}
+	if len(t.UserText) > 0 {
+		m["usertext"] = t.UserText
+	}
+	if len(t.Pictures) > 0 {
+		pictures := make([]map[string]interface{}, len(t.Pictures))
+		for i, p := range t.Pictures {
+			pictures[i] = map[string]interface{}{
+				"mimetype":    p.MIMEType,
+				"type":        PictureTypeName(p.PictureType),
+				"description": p.Description,
+				"size":        len(p.Data),
+			}
+		}
+		m["pictures"] = pictures
+	}
+	return json.Marshal(m)
+}
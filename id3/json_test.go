@@ -0,0 +1,55 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	tags := &SimpleTags{
+		Header:   &ID3v2Header{Version: 3},
+		Title:    "Title",
+		Track:    "3/12",
+		Comments: []Comment{{"eng", "", "A comment"}},
+		Pictures: []Picture{{"image/png", PictureTypeFrontCover, "", []byte("\x89PNG")}},
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		t.Fatalf("Marshal: %s", err)
+	}
+
+	var actual map[string]interface{}
+	if err := json.Unmarshal(b, &actual); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+	expected := map[string]interface{}{
+		"version": 3.0,
+		"title":   "Title",
+		"track":   "3/12",
+		"comment": "A comment",
+		"comments": []interface{}{
+			map[string]interface{}{"language": "eng", "description": "", "text": "A comment"},
+		},
+		"pictures": []interface{}{
+			map[string]interface{}{"mimetype": "image/png", "type": "Cover (front)", "description": "", "size": 4.0},
+		},
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v got %s", expected, b)
+	}
+}
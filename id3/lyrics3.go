@@ -0,0 +1,108 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Lyrics3 v2 tags end with a 6 digit size followed by this marker.
+const (
+	lyrics3Begin        = "LYRICSBEGIN"
+	lyrics3End          = "LYRICS200"
+	lyrics3FooterLength = 6 + len(lyrics3End)
+)
+
+// Lyrics3 v2 fields that extend the truncated ID3v1 fields, keyed by their
+// names in the ID3v2 tag maps.
+var lyrics3Fields = map[string]string{
+	"ETT": "title",
+	"EAR": "artist",
+	"EAL": "album",
+}
+
+// Checks for a Lyrics3 v2 footer at offset and returns where the tag begins.
+//
+// Refer to http://id3.org/Lyrics3v2
+func findLyrics3Tag(reader io.ReadSeeker, offset int64) (int64, bool) {
+	if _, err := reader.Seek(offset, 0); err != nil {
+		return 0, false
+	}
+	footer, err := readBytes(reader, lyrics3FooterLength)
+	if err != nil || string(footer[6:]) != lyrics3End {
+		return 0, false
+	}
+
+	// size covers everything from LYRICSBEGIN up to the footer
+	size, err := strconv.ParseInt(string(footer[:6]), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	start := offset - size
+	if size < int64(len(lyrics3Begin)) || start < 0 {
+		return 0, false
+	}
+	if _, err := reader.Seek(start, 0); err != nil {
+		return 0, false
+	}
+	header, err := readBytes(reader, len(lyrics3Begin))
+	if err != nil || string(header) != lyrics3Begin {
+		return 0, false
+	}
+	return start, true
+}
+
+// Parses the Lyrics3 v2 tag starting at offset. Each field is a 3 letter
+// ID, a 5 digit decimal size and an ISO-8859-1 body, and the fields run up
+// to the footer, whose size digits can't be mistaken for an ID. The LYR
+// field is returned as lyrics and the extended title, artist and album
+// fields in a map keyed like the ID3v2 text frames; other fields are
+// skipped.
+func parseLyrics3Tag(reader io.ReadSeeker, offset int64) (*UnsyncLyrics, map[string]string, error) {
+	if _, err := reader.Seek(offset+int64(len(lyrics3Begin)), 0); err != nil {
+		return nil, nil, err
+	}
+
+	var lyrics *UnsyncLyrics
+	tags := map[string]string{}
+	for {
+		header, err := readBytes(reader, 8)
+		if err != nil {
+			return nil, nil, err
+		}
+		id := string(header[:3])
+		if id[0] >= '0' && id[0] <= '9' {
+			break
+		}
+		size, err := strconv.Atoi(string(header[3:]))
+		if err != nil || size < 0 {
+			return nil, nil, fmt.Errorf("Lyrics3 field %s: invalid size %q", id, header[3:])
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, nil, fmt.Errorf("Lyrics3 field %s: %s", id, err)
+		}
+
+		value := ISO8859_1ToUTF8(data)
+		if id == "LYR" {
+			lyrics = &UnsyncLyrics{Text: value}
+		} else if name, ok := lyrics3Fields[id]; ok && value != "" {
+			tags[name] = value
+		}
+	}
+	return lyrics, tags, nil
+}
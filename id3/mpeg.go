@@ -0,0 +1,110 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"io"
+)
+
+// The fields of an MPEG audio frame header needed to size the audio.
+//
+// Refer to http://www.mp3-tech.org/programmer/frame_header.html
+type mpegHeader struct {
+	bitrate    int // in kbps
+	sampleRate int // in Hz
+	samples    int // per frame
+}
+
+// Bitrates in kbps by bitrate index, for MPEG1 layers I, II and III and
+// then MPEG2 and 2.5 layer I and layers II and III.
+var mpegBitrates = [5][15]int{
+	{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384},
+	{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320},
+	{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256},
+	{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160},
+}
+
+// Sample rates in Hz for MPEG1, MPEG2 and MPEG2.5.
+var mpegSampleRates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// Parses the 4 byte MPEG audio frame header at the start of data. Free
+// format and reserved values are rejected.
+func parseMPEGHeader(data []byte) (*mpegHeader, bool) {
+	if len(data) < 4 || data[0] != 0xFF || data[1]&0xE0 != 0xE0 {
+		return nil, false
+	}
+	version := data[1] >> 3 & 0x03 // 3 = MPEG1, 2 = MPEG2, 0 = MPEG2.5
+	layer := 4 - int(data[1]>>1&0x03)
+	bitrateIndex := int(data[2] >> 4)
+	rateIndex := int(data[2] >> 2 & 0x03)
+	if version == 1 || layer == 4 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+		return nil, false
+	}
+
+	h := &mpegHeader{samples: 1152}
+	switch {
+	case version == 3:
+		h.bitrate = mpegBitrates[layer-1][bitrateIndex]
+		h.sampleRate = mpegSampleRates[0][rateIndex]
+	case layer == 1:
+		h.bitrate = mpegBitrates[3][bitrateIndex]
+	default:
+		h.bitrate = mpegBitrates[4][bitrateIndex]
+	}
+	switch version {
+	case 2:
+		h.sampleRate = mpegSampleRates[1][rateIndex]
+	case 0:
+		h.sampleRate = mpegSampleRates[2][rateIndex]
+	}
+	if layer == 1 {
+		h.samples = 384
+	} else if layer == 3 && version != 3 {
+		h.samples = 576
+	}
+	return h, true
+}
+
+// Bitrate returns the bitrate of the audio in r in kbps. For VBR files,
+// which are recognised by the Xing header in their first frame, it's the
+// average worked out from the header's frame and byte counts. Otherwise
+// it's the bitrate of the first frame, which is also the bitrate of CBR
+// files. VBR files without a Xing header aren't recognised.
+func (t *SimpleTags) Bitrate(r io.ReadSeeker) (kbps int, vbr bool, err error) {
+	frame, err := readFirstMPEGFrame(r)
+	if err != nil {
+		return 0, false, err
+	}
+	h, ok := parseMPEGHeader(frame)
+	if !ok {
+		return 0, false, fmt.Errorf("id3: no MPEG frame at the start of the audio")
+	}
+
+	info, err := parseVBRInfo(frame)
+	if err != nil || info.CBR {
+		return h.bitrate, false, nil
+	}
+	if info.Frames == 0 || info.Bytes == 0 {
+		return h.bitrate, true, nil
+	}
+	seconds := float64(info.Frames*h.samples) / float64(h.sampleRate)
+	return int(float64(info.Bytes)*8/seconds/1000 + 0.5), true, nil
+}
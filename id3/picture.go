@@ -0,0 +1,168 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// A Picture is a decoded APIC frame (PIC in ID3v2.2).
+type Picture struct {
+	MIMEType    string
+	PictureType byte
+	Description string
+	Data        []byte
+}
+
+// Picture types of APIC and PIC frames.
+//
+// Refer to section 4.14 of http://id3.org/id3v2.4.0-frames
+const (
+	PictureTypeOther             byte = 0x00
+	PictureTypeFileIcon          byte = 0x01 // 32x32 pixels, PNG only
+	PictureTypeOtherFileIcon     byte = 0x02
+	PictureTypeFrontCover        byte = 0x03
+	PictureTypeBackCover         byte = 0x04
+	PictureTypeLeaflet           byte = 0x05
+	PictureTypeMedia             byte = 0x06 // e.g. the label side of a CD
+	PictureTypeLeadArtist        byte = 0x07
+	PictureTypeArtist            byte = 0x08
+	PictureTypeConductor         byte = 0x09
+	PictureTypeBand              byte = 0x0a
+	PictureTypeComposer          byte = 0x0b
+	PictureTypeLyricist          byte = 0x0c
+	PictureTypeRecordingLocation byte = 0x0d
+	PictureTypeDuringRecording   byte = 0x0e
+	PictureTypeDuringPerformance byte = 0x0f
+	PictureTypeScreenCapture     byte = 0x10 // a movie or video screen capture
+	PictureTypeBrightFish        byte = 0x11 // a bright coloured fish
+	PictureTypeIllustration      byte = 0x12
+	PictureTypeArtistLogo        byte = 0x13
+	PictureTypePublisherLogo     byte = 0x14
+)
+
+// Descriptions of the picture types as worded in the specification.
+var pictureTypeNames = []string{
+	"Other",
+	"32x32 pixels file icon",
+	"Other file icon",
+	"Cover (front)",
+	"Cover (back)",
+	"Leaflet page",
+	"Media",
+	"Lead artist/lead performer/soloist",
+	"Artist/performer",
+	"Conductor",
+	"Band/Orchestra",
+	"Composer",
+	"Lyricist/text writer",
+	"Recording Location",
+	"During recording",
+	"During performance",
+	"Movie/video screen capture",
+	"A bright coloured fish",
+	"Illustration",
+	"Band/artist logotype",
+	"Publisher/Studio logotype",
+}
+
+// PictureTypeName returns the description of a picture type, or "Unknown"
+// for values outside the specification.
+func PictureTypeName(b byte) string {
+	if int(b) < len(pictureTypeNames) {
+		return pictureTypeNames[b]
+	}
+	return "Unknown"
+}
+
+// SetPictureFromFile appends the image at path to Pictures as a picture of
+// type ptype, e.g. PictureTypeFrontCover, with its MIME type sniffed from
+// the image data. Returns an error if the file isn't an image.
+func (t *SimpleTags) SetPictureFromFile(path string, ptype byte) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	mime := http.DetectContentType(data)
+	if !strings.HasPrefix(mime, "image/") {
+		return fmt.Errorf("id3: %s is not an image: %s", path, mime)
+	}
+	t.Pictures = append(t.Pictures, Picture{
+		MIMEType:    mime,
+		PictureType: ptype,
+		Data:        data,
+	})
+	return nil
+}
+
+// Parses an APIC frame: an encoding byte, a terminated ISO-8859-1 MIME type,
+// the picture type, a description in the frame's encoding and the image
+// data.
+//
+// Refer to section 4.14 of http://id3.org/id3v2.4.0-frames
+func parseID3v2Picture(data []byte) (*Picture, error) {
+	if len(data) < 1 {
+		return nil, fmt.Errorf("picture frame too short: %d bytes", len(data))
+	}
+
+	encoding := data[0]
+	mimeType, rest := splitID3v2String(0, data[1:])
+	if len(rest) < 1 {
+		return nil, fmt.Errorf("picture frame truncated after MIME type")
+	}
+	p := &Picture{MIMEType: ISO8859_1ToUTF8(mimeType), PictureType: rest[0]}
+	return p, parseID3v2PictureDescription(p, encoding, rest[1:])
+}
+
+// Parses a PIC frame, which has a 3 character image format such as "JPG"
+// in place of the MIME type.
+//
+// Refer to section 4.15 of http://id3.org/id3v2-00
+func parseID3v22Picture(data []byte) (*Picture, error) {
+	if len(data) < 5 {
+		return nil, fmt.Errorf("picture frame too short: %d bytes", len(data))
+	}
+
+	p := &Picture{MIMEType: id3v22ImageFormatMIMEType(string(data[1:4])), PictureType: data[4]}
+	return p, parseID3v2PictureDescription(p, data[0], data[5:])
+}
+
+// Parses the terminated description and image data that end both picture
+// frame layouts.
+func parseID3v2PictureDescription(p *Picture, encoding byte, data []byte) error {
+	desc, image := splitID3v2String(encoding, data)
+	if image == nil {
+		return fmt.Errorf("picture frame missing description terminator")
+	}
+	var err error
+	p.Description, err = parseID3v2EncodedString(encoding, desc)
+	p.Data = image
+	return err
+}
+
+// Returns the MIME type of an ID3v2.2 image format.
+func id3v22ImageFormatMIMEType(format string) string {
+	switch strings.ToUpper(format) {
+	case "JPG":
+		return "image/jpeg"
+	case "-->":
+		// the data is a link to the image
+		return "-->"
+	}
+	return "image/" + strings.ToLower(format)
+}
@@ -0,0 +1,150 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPictureTypeName(t *testing.T) {
+	tests := map[byte]string{
+		PictureTypeOther:         "Other",
+		PictureTypeFrontCover:    "Cover (front)",
+		PictureTypeBackCover:     "Cover (back)",
+		PictureTypeArtist:        "Artist/performer",
+		PictureTypePublisherLogo: "Publisher/Studio logotype",
+		0x15:                     "Unknown",
+	}
+	for b, expected := range tests {
+		if name := PictureTypeName(b); name != expected {
+			t.Errorf("0x%02x: expected '%s' got '%s'", b, expected, name)
+		}
+	}
+}
+
+func TestPictures(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00")
+	jpeg := []byte("\xff\xd8\xff\xe0\x00\x10JFIF")
+	expected := []Picture{
+		{"image/png", PictureTypeFrontCover, "Front", png},
+		{"image/jpeg", PictureTypeBackCover, "Bäck", jpeg},
+	}
+
+	front := append([]byte("\x00image/png\x00\x03Front\x00"), png...)
+	back := append([]byte("\x01image/jpeg\x00\x04\xff\xfeB\x00\xe4\x00c\x00k\x00\x00\x00"), jpeg...)
+	v23 := buildID3v2Tag(3, buildID3v2Frame(3, "APIC", front), buildID3v2Frame(3, "APIC", back))
+
+	front = append([]byte("\x00PNG\x03Front\x00"), png...)
+	back = append([]byte("\x00JPG\x04B\xe4ck\x00"), jpeg...)
+	v22 := buildID3v2Tag(2, buildID3v2Frame(2, "PIC", front), buildID3v2Frame(2, "PIC", back))
+
+	for _, tag := range [][]byte{v23, v22} {
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("v2.%d: Read: %s", tag[3], err)
+		}
+		if !reflect.DeepEqual(f.Pictures, expected) {
+			t.Errorf("v2.%d: expected %+v got %+v", tag[3], expected, f.Pictures)
+		}
+	}
+}
+
+func TestTruncatedPicture(t *testing.T) {
+	for _, data := range []string{"", "\x00image/png", "\x00image/png\x00\x03Front"} {
+		tag := buildID3v2Tag(3,
+			buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+			buildID3v2Frame(3, "APIC", []byte(data)))
+		f, err := Read(bytes.NewReader(tag))
+		if err != nil {
+			t.Fatalf("%q: Read: %s", data, err)
+		}
+		if f.Title != "Title" || len(f.Pictures) != 0 || len(f.Warnings) != 1 ||
+			!strings.HasPrefix(f.Warnings[0], "frame APIC at offset 26 skipped") {
+			t.Errorf("%q: expected the APIC frame skipped with a warning got %q, %d, %q", data, f.Title, len(f.Pictures), f.Warnings)
+		}
+		if _, err := ReadStrict(bytes.NewReader(tag)); err == nil || !strings.Contains(err.Error(), "frame APIC at offset") {
+			t.Errorf("%q: ReadStrict: expected an error for the APIC frame got %v", data, err)
+		}
+	}
+}
+
+// A complete 1x1 grayscale PNG.
+var testPNG = []byte("\x89PNG\r\n\x1a\n" +
+	"\x00\x00\x00\x0dIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x00\x00\x00\x00\x3a\x7e\x9b\x55" +
+	"\x00\x00\x00\x0aIDAT\x78\x9c\x63\x60\x00\x00\x00\x02\x00\x01\x48\xaf\xa4\x71" +
+	"\x00\x00\x00\x00IEND\xae\x42\x60\x82")
+
+func TestExtractPNG(t *testing.T) {
+	apic := append([]byte("\x03image/png\x00\x03Cover\x00"), testPNG...)
+	tag := buildID3v2Tag(4, buildID3v2Frame(4, "APIC", apic))
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if len(f.Pictures) != 1 {
+		t.Fatalf("expected one picture got %d", len(f.Pictures))
+	}
+	pic := f.Pictures[0]
+	if pic.MIMEType != "image/png" || pic.PictureType != PictureTypeFrontCover || pic.Description != "Cover" {
+		t.Errorf("got %+v", pic)
+	}
+	if len(pic.Data) != len(testPNG) {
+		t.Errorf("Data: expected %d bytes got %d", len(testPNG), len(pic.Data))
+	}
+	if _, err := png.Decode(bytes.NewReader(pic.Data)); err != nil {
+		t.Errorf("png.Decode: %s", err)
+	}
+}
+
+func TestSetPictureFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "id3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	cover := filepath.Join(dir, "cover.png")
+	notes := filepath.Join(dir, "notes.txt")
+	ioutil.WriteFile(cover, png, 0644)
+	ioutil.WriteFile(notes, []byte("not an image"), 0644)
+
+	tags := &SimpleTags{Title: "Title"}
+	if err := tags.SetPictureFromFile(cover, PictureTypeFrontCover); err != nil {
+		t.Fatalf("SetPictureFromFile: %s", err)
+	}
+	if err := tags.SetPictureFromFile(notes, PictureTypeOther); err == nil {
+		t.Errorf("expected an error for a text file")
+	}
+
+	expected := []Picture{{MIMEType: "image/png", PictureType: PictureTypeFrontCover, Data: png}}
+	for _, version := range []int{3, 4} {
+		f, err := Read(bytes.NewReader(encodeID3v2Tag(tags, WriteOptions{Version: version})))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if !reflect.DeepEqual(f.Pictures, expected) {
+			t.Errorf("v2.%d: expected %+v got %+v", version, expected, f.Pictures)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RVA2 channel type of the master volume.
+const rva2MasterVolume = 0x01
+
+// Parses an RVA2 frame: a terminated ISO-8859-1 identification followed by
+// an adjustment for each channel, returning the master volume adjustment.
+// Each adjustment is a channel type, a signed 16-bit volume in 1/512 dB and
+// a peak volume prefixed by its size in bits. Frames adjusting only other
+// channels are valid, in which case ok is false.
+//
+// Refer to section 4.11 of http://id3.org/id3v2.4.0-frames
+func parseID3v2RelativeVolume(data []byte) (ident string, gain float64, ok bool, err error) {
+	id, rest := splitID3v2String(0, data)
+	ident = ISO8859_1ToUTF8(id)
+	for len(rest) > 0 {
+		if len(rest) < 4 {
+			return "", 0, false, fmt.Errorf("RVA2 frame %q truncated in a channel adjustment", ident)
+		}
+		channel := rest[0]
+		volume := int16(binary.BigEndian.Uint16(rest[1:]))
+		peakBytes := (int(rest[3]) + 7) / 8
+		if channel == rva2MasterVolume {
+			return ident, float64(volume) / 512, true, nil
+		}
+		if len(rest) < 4+peakBytes {
+			return "", 0, false, fmt.Errorf("RVA2 frame %q truncated in a peak volume", ident)
+		}
+		rest = rest[4+peakBytes:]
+	}
+	return ident, 0, false, nil
+}
+
+// Parses a ReplayGain TXXX value such as "-6.50 dB".
+func parseReplayGain(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) > 2 && strings.EqualFold(s[len(s)-2:], "dB") {
+		s = strings.TrimSpace(s[:len(s)-2])
+	}
+	gain, err := strconv.ParseFloat(s, 64)
+	return gain, err == nil
+}
+
+// ReplayGain returns the track and album gain in dB, taken from the
+// "REPLAYGAIN_TRACK_GAIN" and "REPLAYGAIN_ALBUM_GAIN" TXXX frames or else
+// from the "track" and "album" RVA2 frames. A gain that is missing from both
+// is 0, and ok is false if neither gain is present.
+func (t *SimpleTags) ReplayGain() (track float64, album float64, ok bool) {
+	gain := func(desc, ident string) (float64, bool) {
+		if v, found := t.userText(desc); found {
+			if g, valid := parseReplayGain(v); valid {
+				return g, true
+			}
+		}
+		for k, g := range t.RelativeVolume {
+			if strings.EqualFold(k, ident) {
+				return g, true
+			}
+		}
+		return 0, false
+	}
+
+	track, trackOK := gain("REPLAYGAIN_TRACK_GAIN", "track")
+	album, albumOK := gain("REPLAYGAIN_ALBUM_GAIN", "album")
+	return track, album, trackOK || albumOK
+}
@@ -0,0 +1,84 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReplayGain(t *testing.T) {
+	// -6.5 dB on the track's master volume and +1.25 dB on the album's,
+	// which follows a front right channel with a 16-bit peak.
+	trackRVA2 := buildID3v2Frame(4, "RVA2", []byte("track\x00\x01\xf3\x00\x00"))
+	albumRVA2 := buildID3v2Frame(4, "RVA2", []byte("album\x00\x02\x00\x10\x10\x7f\xff\x01\x02\x80\x00"))
+	trackTXXX := buildID3v2Frame(4, "TXXX", []byte("\x03replaygain_track_gain\x00-7.00 dB"))
+	title := buildID3v2Frame(4, "TIT2", []byte("\x03Title"))
+
+	tests := []struct {
+		name         string
+		tag          []byte
+		track, album float64
+		ok           bool
+	}{
+		{"RVA2", buildID3v2Tag(4, trackRVA2, albumRVA2), -6.5, 1.25, true},
+		{"TXXX preferred", buildID3v2Tag(4, trackRVA2, albumRVA2, trackTXXX), -7, 1.25, true},
+		{"TXXX only", buildID3v2Tag(4, trackTXXX), -7, 0, true},
+		{"none", buildID3v2Tag(4, title), 0, 0, false},
+	}
+	for _, test := range tests {
+		f, err := Read(bytes.NewReader(test.tag))
+		if err != nil {
+			t.Fatalf("%s: Read: %s", test.name, err)
+		}
+		track, album, ok := f.ReplayGain()
+		if track != test.track || album != test.album || ok != test.ok {
+			t.Errorf("%s: expected %v, %v, %v got %v, %v, %v",
+				test.name, test.track, test.album, test.ok, track, album, ok)
+		}
+	}
+}
+
+func TestMalformedRVA2(t *testing.T) {
+	// a front right channel adjustment cut off before its peak
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "RVA2", []byte("track\x00\x02\x00\x10")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || len(f.Warnings) != 1 || !strings.HasPrefix(f.Warnings[0], "frame RVA2 at offset 26 skipped") {
+		t.Errorf("expected the RVA2 frame skipped with a warning got %q, %q", f.Title, f.Warnings)
+	}
+	if _, err := ReadStrict(bytes.NewReader(tag)); err == nil || !strings.Contains(err.Error(), "frame RVA2 at offset 26") {
+		t.Errorf("ReadStrict: expected an error for the RVA2 frame got %v", err)
+	}
+}
+
+func TestRVA2WithoutMasterVolume(t *testing.T) {
+	// only the front right channel is adjusted, which is valid
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "RVA2", []byte("track\x00\x02\x00\x10\x10\x7f\xff")),
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || len(f.RelativeVolume) != 0 || len(f.Warnings) != 0 {
+		t.Errorf("expected 'Title' alone got %q, %v, %q", f.Title, f.RelativeVolume, f.Warnings)
+	}
+}
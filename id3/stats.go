@@ -0,0 +1,80 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bufio"
+	"io"
+)
+
+// TagStats summarizes the ID3v2 tag at the front of a stream.
+type TagStats struct {
+	FrameCount int
+
+	// TagBytes is the length of the whole tag, including its header and any
+	// footer, like TagSize.
+	TagBytes int64
+
+	// PaddingBytes is the space left after the last frame.
+	PaddingBytes int
+
+	// LargestFrame is the ID of the frame with the most data, often an
+	// APIC frame. The first is picked on a tie.
+	LargestFrame string
+}
+
+// ReadStats walks the frames of the ID3v2 tag at the front of r without
+// decoding them. Returns ErrNoTags if r doesn't start with an ID3v2 tag.
+// The position of r is restored before returning.
+func ReadStats(r io.ReadSeeker) (*TagStats, error) {
+	origin, err := r.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Seek(origin, 0)
+
+	if _, err := r.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	buf := bufio.NewReader(r)
+	if !hasID3v2Tag(buf) {
+		return nil, ErrNoTags
+	}
+	header, err := parseID3v2Header(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &TagStats{TagBytes: 10 + int64(header.Size)}
+	if header.Footer {
+		s.TagBytes += 10
+	}
+	largest := -1
+	end, _, err := walkID3v2Frames(buf, header, &Options{}, func(f *Frame, offset int) error {
+		s.FrameCount++
+		if len(f.Data) > largest {
+			largest = len(f.Data)
+			s.LargestFrame = f.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if end < int(header.Size) {
+		s.PaddingBytes = int(header.Size) - end
+	}
+	return s, nil
+}
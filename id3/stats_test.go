@@ -0,0 +1,48 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReadStats(t *testing.T) {
+	cover := buildID3v2Frame(3, "APIC", append([]byte("\x00image/png\x00\x03\x00"), bytes.Repeat([]byte{1}, 500)...))
+	tag := buildID3v2Tag(3,
+		buildID3v2Frame(3, "TIT2", []byte("\x00Title")),
+		cover,
+		buildID3v2Frame(3, "TPE1", []byte("\x00Artist")),
+		make([]byte, 1000))
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	r := bytes.NewReader(append(tag, audio...))
+	r.Seek(100, 0)
+
+	s, err := ReadStats(r)
+	if err != nil {
+		t.Fatalf("ReadStats: %s", err)
+	}
+	expected := TagStats{FrameCount: 3, TagBytes: int64(len(tag)), PaddingBytes: 1000, LargestFrame: "APIC"}
+	if *s != expected {
+		t.Errorf("expected %+v got %+v", expected, *s)
+	}
+	if pos, _ := r.Seek(0, 1); pos != 100 {
+		t.Errorf("expected the position to be restored got %d", pos)
+	}
+
+	if _, err := ReadStats(bytes.NewReader(audio)); err != ErrNoTags {
+		t.Errorf("expected ErrNoTags got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// A Violation is an error describing a place where an ID3v2 tag breaks the
+// specification, as reported by ReadStrict.
+type Violation struct {
+	// Offset is counted from the start of the tag header. In tags
+	// unsynchronized as a whole it refers to the decoded tag.
+	Offset int
+
+	// FrameID is empty for violations outside of a frame.
+	FrameID string
+
+	Rule string
+}
+
+func (v *Violation) Error() string {
+	if v.FrameID == "" {
+		return fmt.Sprintf("id3: offset %d: %s", v.Offset, v.Rule)
+	}
+	return fmt.Sprintf("id3: frame %s at offset %d: %s", v.FrameID, v.Offset, v.Rule)
+}
+
+// Frames that may only appear once in a tag besides the text frames.
+//
+// Refer to section 4 of http://id3.org/id3v2.4.0-frames
+var uniqueID3v2Frames = map[string]bool{
+	"ASPI": true, "ETCO": true, "MCDI": true, "MLLT": true, "PCNT": true,
+	"POSS": true, "RVRB": true, "SEEK": true, "SYTC": true,
+	"CNT": true, "ETC": true, "MCI": true, "MLL": true, "REV": true, "STC": true,
+}
+
+// Frames whose data starts with a text encoding byte besides the text
+// frames.
+var encodedID3v2Frames = map[string]bool{
+	"APIC": true, "COMM": true, "COMR": true, "IPLS": true, "OWNE": true,
+	"SYLT": true, "USER": true, "USLT": true, "WXXX": true,
+	"COM": true, "IPL": true, "PIC": true, "SLT": true, "ULT": true, "WXX": true,
+}
+
+// ReadStrict is like Read but first checks the ID3v2 tag at the front of
+// reader against the specification, returning a *Violation for the first
+// problem found. Among others it rejects size bytes that aren't sync-safe,
+// unknown text encodings, repeated frames that must be unique, frames that
+// overrun the tag, frames following the padding, footers that disagree
+// with the header and frames that can't be decoded, all of which Read
+// tolerates. Tags marked experimental
+// are accepted with a warning in Warnings.
+func ReadStrict(reader io.ReadSeeker) (*SimpleTags, error) {
+	if err := checkID3v2Strict(reader); err != nil {
+		return nil, err
+	}
+	return ReadWithOptions(reader, Options{strict: true})
+}
+
+// ReadFileStrict is like ReadStrict but returns the text frames like
+// ReadFile, without the warnings.
+//
+// Deprecated: Use ReadStrict, whose SimpleTags has a field for each of
+// these values alongside the structured frames and warnings.
+func ReadFileStrict(reader io.ReadSeeker) (map[string]string, error) {
+	if err := checkID3v2Strict(reader); err != nil {
+		return nil, err
+	}
+	_, text, err := readTags(reader, &Options{strict: true})
+	if err != nil {
+		return nil, err
+	}
+	return text, nil
+}
+
+// Validates the ID3v2 tag, if any, at the front of reader and then seeks
+// back to where it started.
+func checkID3v2Strict(reader io.ReadSeeker) error {
+	origin, err := reader.Seek(0, 1)
+	if err != nil {
+		return err
+	}
+	buf := bufio.NewReader(reader)
+	if hasID3v2Tag(buf) {
+		if err := validateID3v2Tag(buf); err != nil {
+			return err
+		}
+	}
+	_, err = reader.Seek(origin, 0)
+	return err
+}
+
+// Checks the ID3v2 tag at the front of reader, see ReadStrict.
+func validateID3v2Tag(reader *bufio.Reader) error {
+	data, err := readBytes(reader, 10)
+	if err != nil {
+		return err
+	}
+	for i, b := range data[6:10] {
+		if b&0x80 != 0 {
+			return &Violation{Offset: 6 + i, Rule: "tag size is not sync-safe"}
+		}
+	}
+	header, err := decodeID3v2Header(data)
+	if err != nil {
+		return err
+	}
+	if header.Version < 2 || header.Version > 4 {
+		return &Violation{Offset: 3, Rule: fmt.Sprintf("unknown version 2.%d", header.Version)}
+	}
+	undefined := byte(0x3f)
+	if header.Version == 3 {
+		undefined = 0x1f
+	} else if header.Version == 4 {
+		undefined = 0x0f
+	}
+	if data[5]&undefined != 0 {
+		return &Violation{Offset: 5, Rule: fmt.Sprintf("undefined header flags %#02x", data[5]&undefined)}
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(reader, int64(header.Size)))
+	if err != nil {
+		return err
+	}
+	if len(body) < int(header.Size) {
+		return &Violation{Offset: 6, Rule: fmt.Sprintf("tag size %d overruns the file by %d bytes", header.Size, int(header.Size)-len(body))}
+	}
+	if header.Unsynchronization && header.Version < 4 {
+		body = removeUnsynchronization(body)
+	}
+
+	pos := 0
+	if header.Extended && header.Version > 2 && len(body) >= 4 {
+		if header.Version == 3 {
+			pos = 4 + int(binary.BigEndian.Uint32(body))
+		} else {
+			pos = int(parseID3v2Size(body[:4]))
+		}
+		if pos > len(body) {
+			return &Violation{Offset: 10, Rule: "extended header overruns the tag"}
+		}
+	}
+	if err := validateID3v2Frames(body, pos, header.Version); err != nil {
+		return err
+	}
+	if header.Footer {
+		if err := checkID3v2Footer(reader, header); err != nil {
+			return &Violation{Offset: 10 + int(header.Size), Rule: err.Error()}
+		}
+	}
+	return nil
+}
+
+// Checks each frame of body from pos, followed by nothing but padding.
+func validateID3v2Frames(body []byte, pos int, version int) error {
+	headerLen := id3v2FrameHeaderLength(version)
+	tagLen := 4
+	if version == 2 {
+		tagLen = 3
+	}
+
+	seen := map[string]bool{}
+	for pos < len(body) {
+		offset := 10 + pos
+		if body[pos] == 0 {
+			for i, b := range body[pos:] {
+				if b != 0 {
+					return &Violation{Offset: offset + i, Rule: "data in padding, frames must precede the padding"}
+				}
+			}
+			return nil
+		}
+		if len(body)-pos < headerLen {
+			return &Violation{Offset: offset, Rule: "truncated frame header"}
+		}
+		id := string(body[pos : pos+tagLen])
+		if !hasID3v2Frame(body[pos:], tagLen) {
+			return &Violation{Offset: offset, Rule: fmt.Sprintf("invalid frame ID %q", id)}
+		}
+
+		var size int
+		var flags []byte
+		switch version {
+		case 2:
+			size = int(body[pos+3])<<16 | int(body[pos+4])<<8 | int(body[pos+5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(body[pos+4:]))
+			flags = body[pos+8 : pos+10]
+		case 4:
+			for i, b := range body[pos+4 : pos+8] {
+				if b&0x80 != 0 {
+					return &Violation{Offset: offset + 4 + i, FrameID: id, Rule: "frame size is not sync-safe"}
+				}
+			}
+			size = int(parseID3v2Size(body[pos+4 : pos+8]))
+			flags = body[pos+8 : pos+10]
+		}
+		if end := pos + headerLen + size; end > len(body) {
+			return &Violation{Offset: offset, FrameID: id, Rule: fmt.Sprintf("frame size %d overruns the tag by %d bytes", size, end-len(body))}
+		}
+		data := body[pos+headerLen : pos+headerLen+size]
+
+		unique := uniqueID3v2Frames[id] || (id[0] == 'T' && id != "TXXX" && id != "TXX")
+		if unique && seen[id] {
+			return &Violation{Offset: offset, FrameID: id, Rule: "frame must be unique"}
+		}
+		seen[id] = true
+
+		if err := validateID3v2Encoding(id, data, flags, version); err != nil {
+			return &Violation{Offset: offset + headerLen, FrameID: id, Rule: err.Error()}
+		}
+		pos += headerLen + size
+	}
+	return nil
+}
+
+// Checks the encoding byte of frames that have one. Compressed and
+// encrypted frames are skipped.
+func validateID3v2Encoding(id string, data []byte, flags []byte, version int) error {
+	if id[0] != 'T' && !encodedID3v2Frames[id] {
+		return nil
+	}
+	switch {
+	case version == 3 && flags[1]&0xc0 != 0:
+		return nil
+	case version == 4 && flags[1]&0x0c != 0:
+		return nil
+	case version == 4 && flags[1]&id3v24FrameUnsynchronized != 0:
+		data = removeUnsynchronization(data)
+	}
+	if version == 4 && flags[1]&id3v24FrameDataLength != 0 {
+		if len(data) < 4 {
+			return fmt.Errorf("missing data length indicator")
+		}
+		data = data[4:]
+	}
+
+	if len(data) == 0 {
+		return fmt.Errorf("empty frame")
+	}
+	max := byte(1)
+	if version == 4 {
+		max = 3
+	}
+	if data[0] > max {
+		names := []string{"ISO-8859-1", "UTF-16", "UTF-16BE", "UTF-8"}
+		return fmt.Errorf("unknown text encoding %#02x, expected one of %s", data[0], strings.Join(names[:max+1], ", "))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestReadFileStrict(t *testing.T) {
+	for _, name := range []string{"test_220.mp3", "test_230.mp3", "test_240.mp3"} {
+		f, err := os.Open(path.Join("..", "test", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadFileStrict(f); err != nil {
+			t.Errorf("%s: %s", name, err)
+		}
+		f.Close()
+	}
+
+	// ReadFile tolerates the repeated year, ReadFileStrict doesn't.
+	f, err := os.Open(path.Join("..", "test", "test_iso8859_1.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := ReadFile(f); err != nil {
+		t.Errorf("ReadFile: %s", err)
+	}
+	f.Seek(0, 0)
+	_, err = ReadFileStrict(f)
+	if v, ok := err.(*Violation); !ok || v.FrameID != "TYER" || v.Offset != 153 {
+		t.Errorf("expected a repeated TYER frame at 153 got %v", err)
+	}
+}
+
+func TestReadFileStrictViolations(t *testing.T) {
+	title := buildID3v2Frame(3, "TIT2", []byte("\x00Title"))
+	overrun := buildID3v2Tag(3, title)
+	overrun[10+7] = 0x40
+	notSyncSafe := buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Title")))
+	notSyncSafe[9] |= 0x80
+
+	tests := []struct {
+		name    string
+		tag     []byte
+		frameID string
+		offset  int
+		rule    string
+	}{
+		{"tag size", notSyncSafe, "", 9, "not sync-safe"},
+		{"encoding", buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x03Title"))), "TIT2", 20, "unknown text encoding 0x03"},
+		{"duplicate", buildID3v2Tag(3, title, buildID3v2Frame(3, "TIT2", []byte("\x00Again"))), "TIT2", 26, "must be unique"},
+		{"overrun", overrun, "TIT2", 10, "overruns the tag"},
+		{"padding", buildID3v2Tag(3, make([]byte, 16), title), "", 26, "data in padding"},
+	}
+	for _, test := range tests {
+		_, err := ReadFileStrict(bytes.NewReader(test.tag))
+		v, ok := err.(*Violation)
+		if !ok {
+			t.Errorf("%s: expected a *Violation got %v", test.name, err)
+			continue
+		}
+		if v.FrameID != test.frameID || v.Offset != test.offset || !strings.Contains(v.Rule, test.rule) {
+			t.Errorf("%s: expected %s at %d: %s got %s", test.name, test.frameID, test.offset, test.rule, v)
+		}
+	}
+
+	// the same frames are fine once
+	if _, err := ReadFileStrict(bytes.NewReader(buildID3v2Tag(3, title))); err != nil {
+		t.Errorf("expected no violation got %s", err)
+	}
+	if _, err := ReadStrict(bytes.NewReader(notSyncSafe)); err == nil {
+		t.Error("ReadStrict: expected a violation")
+	}
+}
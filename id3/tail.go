@@ -0,0 +1,147 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// Offsets of the tags appended to the end of a stream, measured from the
+// start of the stream. Absent tags have an offset of -1.
+type tailLayout struct {
+	id3v1    int64 // the 128 byte ID3v1 tag, always last
+	enhanced int64 // the 227 byte "TAG+" block extending the ID3v1 tag
+	lyrics3  int64 // a Lyrics3 v2 tag, which precedes the ID3v1 tag
+	id3v2    int64 // an appended ID3v2 tag located through its footer
+	ape      int64 // the APEv2 tag including its header, if any
+	end      int64 // the first trailing tag, i.e. the end of the audio
+}
+
+// Length of both the APEv2 footer and its optional header.
+const apeFooterLength = 32
+
+// Scans the end of reader for trailing tags. The ID3v1 tag is always the
+// final 128 bytes, possibly preceded by its enhanced block, so a
+// Lyrics3 v2 tag and then an appended ID3v2 tag are looked for
+// immediately before them, or at the very end when there is no ID3v1
+// tag, followed by an APEv2 tag. The reader's position is restored
+// before returning.
+func scanTail(reader io.ReadSeeker) (*tailLayout, error) {
+	origin, err := reader.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Seek(origin, 0)
+
+	size, err := reader.Seek(0, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &tailLayout{id3v1: -1, enhanced: -1, lyrics3: -1, id3v2: -1, ape: -1, end: size}
+	if hasID3v1Tag(reader) {
+		l.id3v1 = size - 128
+		l.end = l.id3v1
+		if l.end >= id3v1EnhancedLength && hasID3v1EnhancedTag(reader, l.end-id3v1EnhancedLength) {
+			l.enhanced = l.end - id3v1EnhancedLength
+			l.end = l.enhanced
+		}
+	}
+
+	if l.end >= int64(lyrics3FooterLength) {
+		if lyrics3, ok := findLyrics3Tag(reader, l.end-int64(lyrics3FooterLength)); ok {
+			l.lyrics3 = lyrics3
+			l.end = lyrics3
+		}
+	}
+
+	if l.end >= 10 {
+		if id3v2, ok := findAppendedID3v2Tag(reader, l.end-10); ok {
+			l.id3v2 = id3v2
+			l.end = id3v2
+		}
+	}
+
+	if l.end >= apeFooterLength {
+		if ape, ok := findAPETag(reader, l.end-apeFooterLength); ok {
+			l.ape = ape
+			l.end = ape
+		}
+	}
+	return l, nil
+}
+
+// Checks for an ID3v2 footer at offset and returns where the header of the
+// tag it closes begins. The footer is a copy of the header with the "ID3"
+// identifier reversed. As "3DI" may well turn up in audio, the footer must
+// be that of an ID3v2.4 tag, the only version with footers, and the header
+// it points at must match it.
+//
+// Refer to section 3.4 of http://id3.org/id3v2.4.0-structure
+func findAppendedID3v2Tag(reader io.ReadSeeker, offset int64) (int64, bool) {
+	if _, err := reader.Seek(offset, 0); err != nil {
+		return 0, false
+	}
+	footer, err := readBytes(reader, 10)
+	if err != nil || string(footer[:3]) != "3DI" || footer[3] != 4 || footer[5]&(1<<4) == 0 {
+		return 0, false
+	}
+	for _, b := range footer[6:] {
+		if b&0x80 != 0 {
+			return 0, false
+		}
+	}
+
+	start := offset - int64(parseID3v2Size(footer[6:])) - 10
+	if start < 0 {
+		return 0, false
+	}
+	if _, err := reader.Seek(start, 0); err != nil {
+		return 0, false
+	}
+	header, err := readBytes(reader, 10)
+	if err != nil || string(header[:3]) != "ID3" || !bytes.Equal(header[3:], footer[3:]) {
+		return 0, false
+	}
+	return start, true
+}
+
+// Checks for an APEv2 footer at offset and returns where the whole APE tag,
+// including its optional header, begins.
+//
+// Refer to http://wiki.hydrogenaud.io/index.php?title=APE_Tags_Header
+func findAPETag(reader io.ReadSeeker, offset int64) (int64, bool) {
+	if _, err := reader.Seek(offset, 0); err != nil {
+		return 0, false
+	}
+	footer, err := readBytes(reader, apeFooterLength)
+	if err != nil || string(footer[:8]) != "APETAGEX" {
+		return 0, false
+	}
+
+	// size covers the items and footer but not the header
+	size := int64(binary.LittleEndian.Uint32(footer[12:16]))
+	flags := binary.LittleEndian.Uint32(footer[20:24])
+	start := offset + apeFooterLength - size
+	if flags&(1<<31) != 0 {
+		start -= apeFooterLength
+	}
+	if size < apeFooterLength || start < 0 {
+		return 0, false
+	}
+	return start, true
+}
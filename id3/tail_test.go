@@ -0,0 +1,273 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Assembles an APEv2 tag with a header around a single item.
+func buildAPETag(key, value string) []byte {
+	item := make([]byte, 8)
+	binary.LittleEndian.PutUint32(item, uint32(len(value)))
+	item = append(item, key...)
+	item = append(item, 0)
+	item = append(item, value...)
+
+	block := func(flags uint32) []byte {
+		b := []byte("APETAGEX")
+		b = binary.LittleEndian.AppendUint32(b, 2000)
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(item)+apeFooterLength))
+		b = binary.LittleEndian.AppendUint32(b, 1)
+		b = binary.LittleEndian.AppendUint32(b, flags)
+		return append(b, make([]byte, 8)...)
+	}
+
+	tag := block(1<<31 | 1<<29)
+	tag = append(tag, item...)
+	return append(tag, block(1<<31)...)
+}
+
+func TestAPEBeforeID3v1(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	ape := buildAPETag("Title", "APE Title")
+	file := append(append(append([]byte{}, audio...), ape...), buildID3v1Tag("V1 Title")...)
+
+	r := bytes.NewReader(file)
+	l, err := scanTail(r)
+	if err != nil {
+		t.Fatalf("scanTail: %s", err)
+	}
+	if l.id3v1 != int64(len(file)-128) {
+		t.Errorf("id3v1: expected %d got %d", len(file)-128, l.id3v1)
+	}
+	if l.ape != int64(len(audio)) {
+		t.Errorf("ape: expected %d got %d", len(audio), l.ape)
+	}
+	if l.end != int64(len(audio)) {
+		t.Errorf("end: expected %d got %d", len(audio), l.end)
+	}
+
+	f, err := Read(r)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "V1 Title" {
+		t.Errorf("Title: expected 'V1 Title' got '%s'", f.Title)
+	}
+
+	// APE without an ID3v1 tag sits at the very end.
+	l, err = scanTail(bytes.NewReader(append(append([]byte{}, audio...), ape...)))
+	if err != nil {
+		t.Fatalf("scanTail: %s", err)
+	}
+	if l.id3v1 != -1 || l.ape != int64(len(audio)) {
+		t.Errorf("expected id3v1 -1 and ape %d got %d and %d", len(audio), l.id3v1, l.ape)
+	}
+}
+
+// Appends a footer to an ID3v2.4 tag built by buildID3v2Tag and sets the
+// footer flag in its header.
+func addID3v2Footer(tag []byte) []byte {
+	tag[5] |= 0x10
+	footer := append([]byte("3DI"), tag[3:10]...)
+	return append(tag, footer...)
+}
+
+func TestFooter(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	tag := addID3v2Footer(buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Title"))))
+	// the footer claims one byte more than the header
+	bad := append([]byte{}, tag...)
+	bad[len(bad)-1]++
+
+	tests := []struct {
+		name    string
+		tag     []byte
+		warning string
+	}{
+		{"valid", tag, ""},
+		{"size", bad, "footer size 17 disagrees with the tag size 16"},
+		{"missing", tag[:len(tag)-10], "footer flag set but no footer follows the tag"},
+	}
+	for _, test := range tests {
+		file := append(append([]byte{}, test.tag...), audio...)
+		f, err := Read(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("%s: Read: %s", test.name, err)
+		}
+		if !f.Header.Footer || f.Title != "Title" {
+			t.Errorf("%s: expected a footered tag titled 'Title' got %t %q", test.name, f.Header.Footer, f.Title)
+		}
+		var warnings []string
+		if test.warning != "" {
+			warnings = []string{test.warning}
+		}
+		if !reflect.DeepEqual(f.Warnings, warnings) {
+			t.Errorf("%s: expected warnings %q got %q", test.name, warnings, f.Warnings)
+		}
+
+		_, err = ReadFileStrict(bytes.NewReader(file))
+		rule := ""
+		if v, ok := err.(*Violation); ok {
+			rule = v.Rule
+		} else if err != nil {
+			rule = err.Error()
+		}
+		if rule != test.warning {
+			t.Errorf("%s: ReadFileStrict: expected %q got %v", test.name, test.warning, err)
+		}
+	}
+}
+
+func TestFooterOnlyTag(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	tag := addID3v2Footer(buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Appended")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Artist"))))
+
+	for _, v1 := range [][]byte{nil, buildID3v1Tag("V1 Title")} {
+		file := append(append(append([]byte{}, audio...), tag...), v1...)
+
+		f, err := Read(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Title != "Appended" || f.Artist != "Artist" {
+			t.Errorf("expected 'Appended'/'Artist' got '%s'/'%s'", f.Title, f.Artist)
+		}
+		if f.Header == nil || f.Header.Version != 4 {
+			t.Errorf("Header: expected v2.4 got %+v", f.Header)
+		}
+
+		m, err := ReadFile(bytes.NewReader(file))
+		if err != nil || m["title"] != "Appended" {
+			t.Errorf("ReadFile: expected title 'Appended' got %v (%v)", m, err)
+		}
+	}
+}
+
+// Builds a Lyrics3 v2 tag holding the given fields in order.
+func buildLyrics3Tag(fields ...[2]string) []byte {
+	tag := []byte(lyrics3Begin)
+	for _, f := range fields {
+		tag = append(tag, fmt.Sprintf("%s%05d%s", f[0], len(f[1]), f[1])...)
+	}
+	return append(tag, fmt.Sprintf("%06d%s", len(tag), lyrics3End)...)
+}
+
+func TestLyrics3(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	lyrics := "[00:01]Caf\xe9 au lait\r\n[00:05]Second line"
+	l3 := buildLyrics3Tag(
+		[2]string{"IND", "01"},
+		[2]string{"LYR", lyrics},
+		[2]string{"ETT", "A Title Far Longer Than Thirty Characters"},
+	)
+	file := append(append(append([]byte{}, audio...), l3...), buildID3v1Tag("A Title Far Longer Than Thirty")...)
+
+	r := bytes.NewReader(file)
+	l, err := scanTail(r)
+	if err != nil {
+		t.Fatalf("scanTail: %s", err)
+	}
+	if l.lyrics3 != int64(len(audio)) || l.end != int64(len(audio)) {
+		t.Errorf("expected lyrics3 and end %d got %d and %d", len(audio), l.lyrics3, l.end)
+	}
+
+	f, err := Read(r)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := []UnsyncLyrics{{Text: "[00:01]Café au lait\r\n[00:05]Second line"}}
+	if !reflect.DeepEqual(f.Lyrics, expected) {
+		t.Errorf("Lyrics: expected %+v got %+v", expected, f.Lyrics)
+	}
+	if f.Title != "A Title Far Longer Than Thirty Characters" {
+		t.Errorf("Title: expected the Lyrics3 title got '%s'", f.Title)
+	}
+}
+
+func TestID3v1Enhanced(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	enhanced := make([]byte, 227)
+	copy(enhanced, "TAG+")
+	enhanced[184] = 3
+	copy(enhanced[215:], "  0:05")
+	copy(enhanced[221:], "4:32\x00\x00")
+	file := append(append(append([]byte{}, audio...), enhanced...), buildID3v1Tag("Title")...)
+
+	r := bytes.NewReader(file)
+	l, err := scanTail(r)
+	if err != nil {
+		t.Fatalf("scanTail: %s", err)
+	}
+	if l.enhanced != int64(len(audio)) || l.end != int64(len(audio)) {
+		t.Errorf("expected enhanced and end %d got %d and %d", len(audio), l.enhanced, l.end)
+	}
+
+	f, err := Read(r)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Title" || f.Speed != 3 || f.StartTime != "0:05" || f.EndTime != "4:32" {
+		t.Errorf("expected 'Title', 3, '0:05', '4:32' got %q, %d, %q, %q", f.Title, f.Speed, f.StartTime, f.EndTime)
+	}
+
+	// without the block nothing is set
+	f, err = Read(bytes.NewReader(append(audio, buildID3v1Tag("Title")...)))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Speed != 0 || f.StartTime != "" || f.EndTime != "" {
+		t.Errorf("expected no enhanced fields got %d, %q, %q", f.Speed, f.StartTime, f.EndTime)
+	}
+}
+
+func TestFalseFooter(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	tag := addID3v2Footer(buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Appended"))))
+
+	// "3DI" in the audio, with and without an "ID3" where its size points
+	fake := append([]byte("3DI"), 4, 0, 0x10, 0, 0, 0, 10)
+	misleading := append(append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), audio[:10]...), fake...)
+	wrongFlags := append(append([]byte{}, tag[:len(tag)-10]...), "3DI\x04\x00\x00"...)
+	wrongFlags = append(wrongFlags, tag[len(tag)-4:]...)
+
+	tests := map[string][]byte{
+		"random":      append(append([]byte{}, audio...), fake...),
+		"misleading":  append(append([]byte{}, audio...), misleading...),
+		"wrong flags": append(append([]byte{}, audio...), wrongFlags...),
+	}
+	for name, file := range tests {
+		l, err := scanTail(bytes.NewReader(file))
+		if err != nil {
+			t.Fatalf("%s: scanTail: %s", name, err)
+		}
+		if l.id3v2 != -1 || l.end != int64(len(file)) {
+			t.Errorf("%s: expected no appended tag got one at %d", name, l.id3v2)
+		}
+	}
+
+	// the genuine footer is still found
+	file := append(append([]byte{}, audio...), tag...)
+	if l, _ := scanTail(bytes.NewReader(file)); l.id3v2 != int64(len(audio)) {
+		t.Errorf("expected the appended tag at %d got %d", len(audio), l.id3v2)
+	}
+}
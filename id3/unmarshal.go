@@ -0,0 +1,115 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Unmarshal reads the ID3v2 tag at the front of r into the struct pointed
+// to by v. Fields are filled from the text frames named by their "id3"
+// struct tag, e.g.
+//
+//	type Track struct {
+//		Title   string   `id3:"TIT2"`
+//		Number  int      `id3:"TRCK"`
+//		Artists []string `id3:"TPE1"`
+//	}
+//
+// Frame IDs are matched as they appear in the tag, so TYER is needed for
+// the year of an ID3v2.3 tag. A string field gets the frame's value, an int
+// field its number, ignoring any "/total" part, and a []string field every
+// value of every frame with the ID. Fields without a frame are left alone.
+func Unmarshal(r io.ReadSeeker, v interface{}) error {
+	return UnmarshalWithOptions(r, v, Options{})
+}
+
+// UnmarshalWithOptions is like Unmarshal but reads the frames according to
+// opts, e.g. failing with ErrExperimental on experimental tags when
+// opts.RejectExperimental is set.
+func UnmarshalWithOptions(r io.ReadSeeker, v interface{}, opts Options) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("id3: Unmarshal needs a non-nil struct pointer, got %T", v)
+	}
+
+	_, frames, err := readAllFrames(r, &opts)
+	if err != nil {
+		return err
+	}
+	byID := map[string][]Frame{}
+	for _, f := range frames {
+		byID[f.ID] = append(byID[f.ID], f)
+	}
+
+	s := rv.Elem()
+	for i := 0; i < s.NumField(); i++ {
+		field := s.Type().Field(i)
+		id := field.Tag.Get("id3")
+		// unexported fields are skipped, as encoding/json does
+		if id == "" || len(byID[id]) == 0 || !s.Field(i).CanSet() {
+			continue
+		}
+		if err := unmarshalFrames(s.Field(i), byID[id]); err != nil {
+			return fmt.Errorf("id3: field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// Decodes the text frames into a string, int or []string value.
+func unmarshalFrames(v reflect.Value, frames []Frame) error {
+	for _, f := range frames {
+		if len(f.Data) == 0 {
+			return fmt.Errorf("frame %s is empty", f.ID)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		s, err := parseID3v2String(frames[0].Data)
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case reflect.Int:
+		s, err := parseID3v2String(frames[0].Data)
+		if err != nil {
+			return err
+		}
+		n, _, err := splitPosition(s)
+		if err != nil {
+			return fmt.Errorf("frame %s: %q is not a number", frames[0].ID, s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var values []string
+		for _, f := range frames {
+			strs, err := parseID3v2Strings(f.Data)
+			if err != nil {
+				return err
+			}
+			values = append(values, strs...)
+		}
+		v.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshal(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", []byte("\x03Title")),
+		buildID3v2Frame(4, "TRCK", []byte("\x033/12")),
+		buildID3v2Frame(4, "TPE1", []byte("\x03Daft Punk\x00Pharrell Williams")),
+		buildID3v2Frame(4, "TBPM", []byte("\x03116")))
+
+	var track struct {
+		Title   string   `id3:"TIT2"`
+		Number  int      `id3:"TRCK"`
+		BPM     int      `id3:"TBPM"`
+		Artists []string `id3:"TPE1"`
+		Album   string   `id3:"TALB"`
+		Ignored string
+	}
+	track.Album = "Unchanged"
+	if err := Unmarshal(bytes.NewReader(tag), &track); err != nil {
+		t.Fatalf("Unmarshal: %s", err)
+	}
+	if track.Title != "Title" || track.Number != 3 || track.BPM != 116 || track.Album != "Unchanged" {
+		t.Errorf("got %+v", track)
+	}
+	if expected := []string{"Daft Punk", "Pharrell Williams"}; !reflect.DeepEqual(track.Artists, expected) {
+		t.Errorf("Artists: expected %q got %q", expected, track.Artists)
+	}
+
+	var bad struct {
+		Title float64 `id3:"TIT2"`
+	}
+	if err := Unmarshal(bytes.NewReader(tag), &bad); err == nil {
+		t.Error("expected an error for a float64 field")
+	}
+	var notNumber struct {
+		Title int `id3:"TIT2"`
+	}
+	if err := Unmarshal(bytes.NewReader(tag), &notNumber); err == nil {
+		t.Error("expected an error for a non-numeric value")
+	}
+	if err := Unmarshal(bytes.NewReader(tag), track); err == nil {
+		t.Error("expected an error for a non-pointer")
+	}
+
+	var unexported struct {
+		Title string `id3:"TIT2"`
+		title string `id3:"TIT2"`
+	}
+	if err := Unmarshal(bytes.NewReader(tag), &unexported); err != nil || unexported.Title != "Title" || unexported.title != "" {
+		t.Errorf("unexported field: got %+v, %v", unexported, err)
+	}
+}
+
+func TestUnmarshalEmptyFrame(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "TIT2", nil),
+		buildID3v2Frame(4, "TRCK", nil))
+
+	var title struct {
+		Title string `id3:"TIT2"`
+	}
+	if err := Unmarshal(bytes.NewReader(tag), &title); err == nil {
+		t.Error("string: expected an error for an empty frame")
+	}
+	var track struct {
+		Number int `id3:"TRCK"`
+	}
+	if err := Unmarshal(bytes.NewReader(tag), &track); err == nil {
+		t.Error("int: expected an error for an empty frame")
+	}
+}
+
+func TestUnmarshalExperimental(t *testing.T) {
+	tag := buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Title")))
+	tag[5] |= 1 << 5
+
+	var track struct {
+		Title string `id3:"TIT2"`
+	}
+	if err := Unmarshal(bytes.NewReader(tag), &track); err != nil || track.Title != "Title" {
+		t.Errorf("Unmarshal: got %+v, %v", track, err)
+	}
+	err := UnmarshalWithOptions(bytes.NewReader(tag), &track, Options{RejectExperimental: true})
+	if err != ErrExperimental {
+		t.Errorf("expected ErrExperimental got %v", err)
+	}
+}
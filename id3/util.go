@@ -59,16 +59,27 @@ func toUTF16(data []byte) ([]uint16, error) {
 	return s, nil
 }
 
+// Reads big endian UTF-16 code units written without a BOM. A trailing odd
+// byte is dropped.
+func toUTF16BE(data []byte) []uint16 {
+	s := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		s = append(s, binary.BigEndian.Uint16(data[i:]))
+	}
+	return s
+}
+
 func readBytes(reader io.Reader, c int) ([]byte, error) {
 	b := make([]byte, c)
 
-	n, err := reader.Read(b)
+	// A single Read may return less than is available, e.g. from a pipe.
+	n, err := io.ReadFull(reader, b)
+	if err == io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("short read, %d/%d", n, c)
+	}
 	if err != nil {
 		return nil, err
 	}
-	if n != c {
-		return nil, fmt.Errorf("short read, %d/%d", n, c)
-	}
 	return b, nil
 }
 
@@ -88,3 +99,13 @@ func skipBytes(reader *bufio.Reader, c int) error {
 	}
 	return nil
 }
+
+// Peeks at the buffer to see if it starts with an MPEG audio frame sync: 11
+// set bits.
+func hasMPEGSync(reader *bufio.Reader) bool {
+	data, err := reader.Peek(2)
+	if err != nil {
+		return false
+	}
+	return data[0] == 0xFF && data[1]&0xE0 == 0xE0
+}
@@ -0,0 +1,159 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrNoVBRHeader is returned by ReadVBRInfo when the first MPEG frame has
+// no Xing or Info header.
+var ErrNoVBRHeader = errors.New("id3: no Xing/Info header")
+
+// Xing header flags.
+const (
+	xingFrames  = 0x1
+	xingBytes   = 0x2
+	xingTOC     = 0x4
+	xingQuality = 0x8
+)
+
+// VBRInfo holds the Xing or Info header found in the first MPEG frame of
+// VBR (Xing) and CBR (Info) files written by most encoders.
+type VBRInfo struct {
+	// Whether the header was labelled "Info" rather than "Xing", which LAME
+	// does for CBR files.
+	CBR bool
+
+	// Frames and Bytes are the number of audio frames and bytes in the
+	// file, or 0 if not present.
+	Frames int
+	Bytes  int
+
+	// Encoder is the short version string of the LAME extension, e.g.
+	// "LAME3.99r", or empty if there is none.
+	Encoder string
+
+	// EncoderDelay and EncoderPadding are the number of samples added at
+	// the start and end of the audio by the encoder, which players skip for
+	// gapless playback. Both are 0 without a LAME extension.
+	EncoderDelay   int
+	EncoderPadding int
+}
+
+// ReadVBRInfo reads the Xing or Info header from the first MPEG frame
+// following the ID3v2 tag of r. Returns ErrNoVBRHeader if there is none.
+func ReadVBRInfo(r io.ReadSeeker) (*VBRInfo, error) {
+	frame, err := readFirstMPEGFrame(r)
+	if err != nil {
+		return nil, err
+	}
+	return parseVBRInfo(frame)
+}
+
+// Reads the start of the first MPEG frame following the ID3v2 tag of r.
+func readFirstMPEGFrame(r io.ReadSeeker) ([]byte, error) {
+	start, err := AudioOffset(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(start, 0); err != nil {
+		return nil, err
+	}
+
+	// The header, side information, Xing fields and LAME extension all fit
+	// in the smallest frame.
+	frame := make([]byte, 4+32+120+36)
+	n, err := io.ReadFull(r, frame)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return frame[:n], nil
+}
+
+// Parses the Xing or Info header of a frame starting with its MPEG header.
+func parseVBRInfo(frame []byte) (*VBRInfo, error) {
+	if len(frame) < 4 || frame[0] != 0xFF || frame[1]&0xE0 != 0xE0 {
+		return nil, ErrNoVBRHeader
+	}
+
+	// The header follows the side information, whose size depends on the
+	// MPEG version and whether the frame is mono.
+	mpeg1 := frame[1]>>3&0x03 == 0x03
+	mono := frame[3]>>6 == 0x03
+	pos := 4
+	switch {
+	case mpeg1 && mono:
+		pos += 17
+	case mpeg1:
+		pos += 32
+	case mono:
+		pos += 9
+	default:
+		pos += 17
+	}
+	if len(frame) < pos+8 {
+		return nil, ErrNoVBRHeader
+	}
+
+	info := &VBRInfo{}
+	switch string(frame[pos : pos+4]) {
+	case "Xing":
+	case "Info":
+		info.CBR = true
+	default:
+		return nil, ErrNoVBRHeader
+	}
+	flags := binary.BigEndian.Uint32(frame[pos+4:])
+	pos += 8
+
+	if flags&xingFrames != 0 {
+		if len(frame) < pos+4 {
+			return nil, ErrNoVBRHeader
+		}
+		info.Frames = int(binary.BigEndian.Uint32(frame[pos:]))
+		pos += 4
+	}
+	if flags&xingBytes != 0 {
+		if len(frame) < pos+4 {
+			return nil, ErrNoVBRHeader
+		}
+		info.Bytes = int(binary.BigEndian.Uint32(frame[pos:]))
+		pos += 4
+	}
+	if flags&xingTOC != 0 {
+		pos += 100
+	}
+	if flags&xingQuality != 0 {
+		pos += 4
+	}
+
+	// The LAME extension starts with a 9 byte version string and holds the
+	// two 12-bit sample counts 21 bytes in.
+	if len(frame) < pos+24 {
+		return info, nil
+	}
+	lame := frame[pos : pos+24]
+	// ffmpeg writes the same extension labelled "Lavc".
+	if string(lame[:4]) != "LAME" && string(lame[:4]) != "Lavc" {
+		return info, nil
+	}
+	info.Encoder = string(lame[:9])
+	info.EncoderDelay = int(lame[21])<<4 | int(lame[22])>>4
+	info.EncoderPadding = int(lame[22]&0x0F)<<8 | int(lame[23])
+	return info, nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Builds an MPEG1 Layer III stereo frame holding an Info header with a LAME
+// extension as written by "lame -b 128" 3.99.
+func buildLAMEFrame(delay, padding int) []byte {
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xff, 0xfb, 0x90, 0x00})
+	xing := frame[4+32:]
+	copy(xing, "Info\x00\x00\x00\x0f")
+	copy(xing[8:], []byte{0x00, 0x00, 0x01, 0x2c})  // frames
+	copy(xing[12:], []byte{0x00, 0x01, 0xe2, 0x40}) // bytes
+	lame := xing[8+4+4+100+4:]
+	copy(lame, "LAME3.99r")
+	lame[21] = byte(delay >> 4)
+	lame[22] = byte(delay<<4) | byte(padding>>8)
+	lame[23] = byte(padding)
+	return frame
+}
+
+func TestReadVBRInfo(t *testing.T) {
+	tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")))
+	file := append(tag, buildLAMEFrame(576, 1536)...)
+
+	info, err := ReadVBRInfo(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("ReadVBRInfo: %s", err)
+	}
+	expected := VBRInfo{
+		CBR:            true,
+		Frames:         300,
+		Bytes:          123456,
+		Encoder:        "LAME3.99r",
+		EncoderDelay:   576,
+		EncoderPadding: 1536,
+	}
+	if *info != expected {
+		t.Errorf("expected %+v got %+v", expected, *info)
+	}
+
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 128)
+	if _, err := ReadVBRInfo(bytes.NewReader(audio)); err != ErrNoVBRHeader {
+		t.Errorf("expected ErrNoVBRHeader got %v", err)
+	}
+}
+
+func TestBitrate(t *testing.T) {
+	vbr := buildLAMEFrame(576, 1536)
+	copy(vbr[4+32:], "Xing")
+	copy(vbr[4+32+8:], []byte{0x00, 0x00, 0x03, 0xe8})  // 1000 frames
+	copy(vbr[4+32+12:], []byte{0x00, 0x09, 0x90, 0xfb}) // 626939 bytes
+
+	tests := []struct {
+		name  string
+		audio []byte
+		kbps  int
+		vbr   bool
+	}{
+		{"xing", vbr, 192, true},
+		{"info", buildLAMEFrame(576, 1536), 128, false},
+		{"plain", bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 128), 128, false},
+	}
+	for _, test := range tests {
+		tag := buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title")))
+		file := bytes.NewReader(append(tag, test.audio...))
+		kbps, isVBR, err := new(SimpleTags).Bitrate(file)
+		if err != nil {
+			t.Errorf("%s: Bitrate: %s", test.name, err)
+			continue
+		}
+		if kbps != test.kbps || isVBR != test.vbr {
+			t.Errorf("%s: expected %d, %v got %d, %v", test.name, test.kbps, test.vbr, kbps, isVBR)
+		}
+	}
+
+	if _, _, err := new(SimpleTags).Bitrate(bytes.NewReader(make([]byte, 256))); err == nil {
+		t.Error("expected an error without an MPEG frame")
+	}
+}
@@ -0,0 +1,629 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// A text frame to be written, holding one or more values.
+type textFrame struct {
+	id     string
+	values []string
+}
+
+// The text frames written for the fields of t, using ID3v2.4 frame IDs.
+// Empty fields are omitted.
+func id3v2TextFrames(t *SimpleTags) []textFrame {
+	// Artists is only written while it agrees with Artist, so that an
+	// edit to Artist after Read isn't lost.
+	artists := []string{t.Artist}
+	if len(t.Artists) > 0 && (t.Artist == "" || strings.Join(t.Artists, "/") == t.Artist) {
+		artists = t.Artists
+	}
+	var originalRelease string
+	if !t.OriginalRelease.IsZero() {
+		originalRelease = formatID3v2Timestamp(t.OriginalRelease)
+	}
+
+	var frames []textFrame
+	for _, f := range []textFrame{
+		{"TIT2", []string{t.Title}},
+		{"TPE1", artists},
+		{"TALB", []string{t.Album}},
+		{"TDRC", []string{t.Year}},
+		{"TRCK", []string{t.Track}},
+		{"TPOS", []string{t.Disc}},
+		{"TCON", []string{t.Genre}},
+		{"TLEN", []string{t.Length}},
+		{"TPUB", []string{t.Publisher}},
+		{"TBPM", []string{t.BPM}},
+		{"TMOO", []string{t.Mood}},
+		{"TCOM", []string{t.Composer}},
+		{"TCOP", []string{t.Copyright}},
+		{"TENC", []string{t.EncodedBy}},
+		{"TSSE", []string{t.Encoder}},
+		{"TLAN", []string{t.Language}},
+		{"TEXT", []string{t.Writer}},
+		{"TOPE", []string{t.OriginalArtist}},
+		{"TMED", []string{t.Media}},
+		{"TGID", []string{t.PodcastID}},
+		{"WFED", []string{t.PodcastFeed}},
+		{"TDES", []string{t.PodcastDescription}},
+		{"TKWD", []string{t.Keywords}},
+		{"TDOR", []string{originalRelease}},
+		{"TDRL", []string{t.ReleaseTime}},
+	} {
+		if len(f.values) > 1 || f.values[0] != "" {
+			frames = append(frames, f)
+		}
+	}
+	return frames
+}
+
+// ID3v2.4 frames that ID3v2.3 knows by another ID. Frames mapped to "" have
+// no ID3v2.3 equivalent and are dropped.
+var id3v23FrameIDs = map[string]string{
+	"TDRC": "TYER",
+	"TDOR": "TORY",
+	"TMOO": "",
+	"TDRL": "",
+}
+
+// Picks the text encoding for strings sharing one encoding byte: UTF-8 for
+// ID3v2.4 and for ID3v2.3, which lacks UTF-8, ISO-8859-1 unless a string
+// needs UTF-16.
+func id3v2Encoding(version int, strs ...string) byte {
+	if version == 4 {
+		return 3
+	}
+	for _, s := range strs {
+		for _, r := range s {
+			if r > 0xff {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// Encodes s in the given text encoding. UTF-16 is written little endian
+// with a BOM and runes outside ISO-8859-1 are replaced by '?' in it.
+func encodeID3v2String(encoding byte, s string) []byte {
+	switch encoding {
+	case 0:
+		b := make([]byte, 0, len(s))
+		for _, r := range s {
+			if r > 0xff {
+				r = '?'
+			}
+			b = append(b, byte(r))
+		}
+		return b
+	case 1:
+		b := []byte{0xff, 0xfe}
+		for _, u := range utf16.Encode([]rune(s)) {
+			b = append(b, byte(u), byte(u>>8))
+		}
+		return b
+	}
+	return []byte(s)
+}
+
+// Returns the string terminator for the given text encoding.
+func id3v2Terminator(encoding byte) []byte {
+	if encoding == 1 || encoding == 2 {
+		return []byte{0, 0}
+	}
+	return []byte{0}
+}
+
+// Encodes the body of a text frame. ID3v2.4 separates multiple values with
+// the terminator while ID3v2.3, which has no such notion, joins them with
+// "/" as it does for multiple artists.
+func encodeID3v2TextFrame(version int, values []string) []byte {
+	if version != 4 {
+		values = []string{strings.Join(values, "/")}
+	}
+	encoding := id3v2Encoding(version, values...)
+	data := []byte{encoding}
+	for i, v := range values {
+		if i > 0 {
+			data = append(data, id3v2Terminator(encoding)...)
+		}
+		data = append(data, encodeID3v2String(encoding, v)...)
+	}
+	return data
+}
+
+// Encodes a frame header followed by data. Sizes are sync-safe in ID3v2.4
+// and plain big endian integers in ID3v2.3.
+func encodeID3v2Frame(version int, id string, data []byte) []byte {
+	f := make([]byte, 0, 10+len(data))
+	f = append(f, encodeID3v2FrameHeader(version, id, len(data))...)
+	return append(f, data...)
+}
+
+// Encodes the header of a frame whose body is size bytes long.
+func encodeID3v2FrameHeader(version int, id string, size int) []byte {
+	f := make([]byte, 0, 10)
+	f = append(f, id...)
+	if version == 4 {
+		f = append(f, encodeID3v2Size(int32(size))...)
+	} else {
+		f = binary.BigEndian.AppendUint32(f, uint32(size))
+	}
+	return append(f, 0, 0)
+}
+
+// WriteOptions controls how tags are encoded. The zero value writes an
+// ID3v2.4 tag.
+type WriteOptions struct {
+	// Version is the ID3v2 major version to write, 3 or 4. Defaults to 4.
+	Version int
+
+	// Unsynchronize inserts a 0x00 after every 0xFF that could be mistaken
+	// for an MPEG frame sync and sets the unsynchronization flag, on each
+	// affected frame as well in ID3v2.4. Only needed for old players that
+	// scan tags for audio.
+	Unsynchronize bool
+
+	// Include limits the frames written to those with the listed IDs when
+	// not empty, and frames with IDs listed in Exclude are never written.
+	// IDs are those of the version written, e.g. TYER for an ID3v2.3 year.
+	Include []string
+	Exclude []string
+}
+
+// Reports whether frames with the given ID are written.
+func (o *WriteOptions) emits(id string) bool {
+	for _, x := range o.Exclude {
+		if x == id {
+			return false
+		}
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	for _, i := range o.Include {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteTag writes tags to w as an ID3v2 tag without padding.
+func WriteTag(w io.Writer, tags *SimpleTags, opts WriteOptions) error {
+	_, err := w.Write(encodeID3v2Tag(tags, opts))
+	return err
+}
+
+// WriteMinimal writes an ID3v2.4 tag holding only the title and artist to
+// w, for quickly tagging generated audio.
+func WriteMinimal(w io.Writer, title, artist string) error {
+	return WriteTag(w, &SimpleTags{Title: title, Artist: artist}, WriteOptions{})
+}
+
+// EncodedSize returns the number of bytes, header included, of the ID3v2
+// tag that WriteTag would write for tags with opts, e.g. to check whether
+// it fits in the padding of an existing tag. The size stored in the tag
+// header is 10 bytes less.
+func EncodedSize(tags *SimpleTags, opts WriteOptions) int {
+	version := opts.Version
+	if version == 0 {
+		version = 4
+	}
+	size := 10
+	// ID3v2.3 unsynchronizes the frame headers too
+	var body [][]byte
+	for _, f := range id3v2Frames(tags, version) {
+		if !opts.emits(f.id) {
+			continue
+		}
+		n := len(f.data) + len(f.picture)
+		if opts.Unsynchronize && version == 4 {
+			n += unsynchronizationGrowth(f.data, f.picture)
+		} else if opts.Unsynchronize {
+			body = append(body, encodeID3v2FrameHeader(version, f.id, n), f.data, f.picture)
+		}
+		size += id3v2FrameHeaderLength(version) + n
+	}
+	return size + unsynchronizationGrowth(body...)
+}
+
+// A frame to be written. Picture data is kept apart from the rest of the
+// frame body so that the size of a tag can be found without copying it.
+type outFrame struct {
+	id      string
+	data    []byte
+	picture []byte
+}
+
+// The frames written for the fields, comments and pictures of t in the
+// given version, before unsynchronization or filtering by WriteOptions.
+func id3v2Frames(t *SimpleTags, version int) []outFrame {
+	var frames []outFrame
+	for _, f := range id3v2TextFrames(t) {
+		id := f.id
+		if version == 3 {
+			if v23, ok := id3v23FrameIDs[id]; ok {
+				id = v23
+			}
+			if id == "" {
+				continue
+			}
+			// ID3v2.3 timestamps are a plain year.
+			if (id == "TYER" || id == "TORY") && len(f.values[0]) > 4 {
+				f.values = []string{f.values[0][:4]}
+			}
+		}
+		frames = append(frames, outFrame{id: id, data: encodeID3v2TextFrame(version, f.values)})
+	}
+	// Comment replaces the text of the main comment, which Read copies it
+	// from, so that editing it after Read takes effect.
+	comments := t.Comments
+	if t.Comment != "" {
+		main := -1
+		for i, c := range comments {
+			if c.Description == "" {
+				main = i
+				break
+			}
+		}
+		if main < 0 {
+			comments = append([]Comment{{"eng", "", t.Comment}}, comments...)
+		} else {
+			comments = append([]Comment{}, comments...)
+			comments[main].Text = t.Comment
+		}
+	}
+	for _, c := range comments {
+		lang := c.Language
+		if len(lang) != 3 {
+			lang = "XXX"
+		}
+		encoding := id3v2Encoding(version, c.Description, c.Text)
+		data := []byte{encoding}
+		data = append(data, lang...)
+		data = append(data, encodeID3v2String(encoding, c.Description)...)
+		data = append(data, id3v2Terminator(encoding)...)
+		data = append(data, encodeID3v2String(encoding, c.Text)...)
+		frames = append(frames, outFrame{id: "COMM", data: data})
+	}
+	for _, p := range t.Pictures {
+		encoding := id3v2Encoding(version, p.Description)
+		data := []byte{encoding}
+		data = append(data, encodeID3v2String(0, p.MIMEType)...)
+		data = append(data, 0, p.PictureType)
+		data = append(data, encodeID3v2String(encoding, p.Description)...)
+		data = append(data, id3v2Terminator(encoding)...)
+		frames = append(frames, outFrame{id: "APIC", data: data, picture: p.Data})
+	}
+	return frames
+}
+
+// Encodes the text fields and comments of tags as an ID3v2 tag without
+// padding.
+func encodeID3v2Tag(t *SimpleTags, opts WriteOptions) []byte {
+	version := opts.Version
+	if version == 0 {
+		version = 4
+	}
+	unsynchronized := false
+
+	var frames bytes.Buffer
+	writeFrame := func(id string, data []byte) {
+		if !opts.emits(id) {
+			return
+		}
+		if opts.Unsynchronize && version == 4 {
+			if u := applyUnsynchronization(data); len(u) != len(data) {
+				f := encodeID3v2Frame(version, id, u)
+				f[9] |= id3v24FrameUnsynchronized
+				frames.Write(f)
+				unsynchronized = true
+				return
+			}
+		}
+		frames.Write(encodeID3v2Frame(version, id, data))
+	}
+
+	for _, f := range id3v2Frames(t, version) {
+		writeFrame(f.id, append(f.data, f.picture...))
+	}
+
+	// ID3v2.3 unsynchronizes everything after the header at once.
+	body := frames.Bytes()
+	if opts.Unsynchronize && version == 3 {
+		if u := applyUnsynchronization(body); len(u) != len(body) {
+			body = u
+			unsynchronized = true
+		}
+	}
+
+	var flags byte
+	if unsynchronized {
+		flags |= 1 << 7
+	}
+	tag := make([]byte, 0, 10+len(body))
+	tag = append(tag, "ID3"...)
+	tag = append(tag, byte(version), 0, flags)
+	tag = append(tag, encodeID3v2Size(int32(len(body)))...)
+	return append(tag, body...)
+}
+
+// Counts the zero bytes applyUnsynchronization would insert into parts
+// joined together, without joining them.
+func unsynchronizationGrowth(parts ...[]byte) int {
+	n := 0
+	pendingFF := false
+	for _, p := range parts {
+		if len(p) == 0 {
+			continue
+		}
+		if pendingFF && (p[0] == 0 || p[0] >= 0xe0) {
+			n++
+		}
+		for i := 0; i+1 < len(p); i++ {
+			if p[i] == 0xff && (p[i+1] == 0 || p[i+1] >= 0xe0) {
+				n++
+			}
+		}
+		pendingFF = p[len(p)-1] == 0xff
+	}
+	// a trailing 0xFF is escaped too
+	if pendingFF {
+		n++
+	}
+	return n
+}
+
+// Inserts a 0x00 after every 0xFF followed by a byte that would make them a
+// false sync (%111xxxxx) or by 0x00, as well as after a final 0xFF, so that
+// removeUnsynchronization recovers data exactly.
+//
+// Refer to section 6.1 of http://id3.org/id3v2.4.0-structure
+func applyUnsynchronization(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	for i, b := range data {
+		out = append(out, b)
+		if b == 0xff && (i+1 == len(data) || data[i+1] == 0 || data[i+1] >= 0xe0) {
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+// Pads an encoded ID3v2 tag with zeros to size bytes.
+func padID3v2Tag(tag []byte, size int) []byte {
+	tag = append(tag, make([]byte, size-len(tag))...)
+	copy(tag[6:10], encodeID3v2Size(int32(size-10)))
+	return tag
+}
+
+// TruncationReport lists the fields, named as in Map, that an ID3v1 tag
+// couldn't hold in full.
+type TruncationReport struct {
+	// Truncated fields were cut to the length of their ID3v1 field: 30
+	// bytes, 28 for a comment next to a track number and 4 for the year.
+	Truncated []string
+
+	// Lossy fields had characters outside ISO-8859-1 replaced by "?".
+	Lossy []string
+}
+
+// WriteID3v1Report writes tags to w as a 128 byte ID3v1.1 tag, reporting
+// the fields that were truncated or lost characters on the way.
+func WriteID3v1Report(w io.Writer, tags *SimpleTags) (TruncationReport, error) {
+	var report TruncationReport
+	_, err := w.Write(encodeID3v1Tag(tags, &report))
+	return report, err
+}
+
+// Encodes the fields of tags as an ID3v1.1 tag, truncating them to fit.
+// The genre is written as its ID3v1 code, or 255 if it has none. Fields that
+// don't fit are added to report unless it's nil.
+func encodeID3v1Tag(t *SimpleTags, report *TruncationReport) []byte {
+	if report == nil {
+		report = &TruncationReport{}
+	}
+	field := func(dst []byte, key, s string) {
+		b := encodeID3v2String(0, s)
+		if len(b) > len(dst) {
+			report.Truncated = append(report.Truncated, key)
+		}
+		for _, r := range s {
+			if r > 0xff {
+				report.Lossy = append(report.Lossy, key)
+				break
+			}
+		}
+		copy(dst, b)
+	}
+
+	tag := make([]byte, 128)
+	copy(tag, "TAG")
+	field(tag[3:33], "title", t.Title)
+	field(tag[33:63], "artist", t.Artist)
+	field(tag[63:93], "album", t.Album)
+	field(tag[93:97], "year", t.Year)
+
+	// ID3v1.1 takes the last 2 bytes of the comment for the track number
+	comment := tag[97:127]
+	if n, _, _ := splitPosition(t.Track); n > 0 && n < 256 {
+		comment = tag[97:125]
+		tag[126] = byte(n)
+	}
+	field(comment, "comment", t.Map()["comment"])
+
+	tag[127] = 255
+	genre := convertID3v1Genre(t.Genre, id3v1Genres)
+	for i, g := range id3v1Genres {
+		if strings.EqualFold(g, genre) {
+			tag[127] = byte(i)
+			break
+		}
+	}
+	return tag
+}
+
+// WriteFile writes tags to rw as an ID3v2.4 tag, replacing the ID3v2 tag at
+// the front, if any. Everything after the old tag, the audio and any
+// trailing tags, is kept and moved if the new tag doesn't fit in place of
+// the old one. As rw can't be truncated, a smaller tag is padded to the old
+// tag's length.
+func WriteFile(rw io.ReadWriteSeeker, tags *SimpleTags) error {
+	return replaceID3v2Tag(rw, encodeID3v2Tag(tags, WriteOptions{}), nil)
+}
+
+// WriteBoth writes tags to rw as both an ID3v2.3 tag at the front and an
+// ID3v1 tag at the end, replacing any existing ones, so that the ID3v1
+// fields are truncated copies of the ID3v2 ones. The audio in between is
+// moved if the new ID3v2 tag doesn't fit in place of the old one. As rw
+// can't be truncated, a smaller tag is padded to the old tag's length.
+func WriteBoth(rw io.ReadWriteSeeker, tags *SimpleTags) error {
+	return replaceID3v2Tag(rw, encodeID3v2Tag(tags, WriteOptions{Version: 3}), encodeID3v1Tag(tags, nil))
+}
+
+// Writes tag in place of the ID3v2 tag at the front of rw, see WriteFile.
+// Any ID3v1 tag is replaced by v1 unless v1 is nil.
+func replaceID3v2Tag(rw io.ReadWriteSeeker, tag []byte, v1 []byte) error {
+	if _, err := rw.Seek(0, 0); err != nil {
+		return err
+	}
+	oldLen, err := TagSize(rw)
+	if err != nil {
+		return err
+	}
+	end, err := rw.Seek(0, 2)
+	if err != nil {
+		return err
+	}
+	if v1 != nil && hasID3v1Tag(rw) {
+		end -= 128
+	}
+	if end < oldLen {
+		return fmt.Errorf("ID3v2 tag overruns the stream: %d/%d bytes", oldLen, end)
+	}
+
+	audio := make([]byte, end-oldLen)
+	if _, err := rw.Seek(oldLen, 0); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(rw, audio); err != nil {
+		return err
+	}
+
+	if int64(len(tag)) < oldLen {
+		tag = padID3v2Tag(tag, int(oldLen))
+	}
+	if _, err := rw.Seek(0, 0); err != nil {
+		return err
+	}
+	for _, b := range [][]byte{tag, audio, v1} {
+		if _, err := rw.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StripID3v2 removes the ID3v2 tag, including any footer, from the front
+// of rw by moving the rest of the stream, ID3v1 tag and all, forward. rw
+// must also have a Truncate(size int64) error method, as *os.File does, to
+// drop the bytes left over at the end.
+func StripID3v2(rw io.ReadWriteSeeker) error {
+	if _, err := rw.Seek(0, 0); err != nil {
+		return err
+	}
+	tagLen, err := TagSize(rw)
+	if err != nil || tagLen == 0 {
+		return err
+	}
+	t, ok := rw.(interface{ Truncate(size int64) error })
+	if !ok {
+		return fmt.Errorf("StripID3v2: %T can't be truncated", rw)
+	}
+
+	buf := make([]byte, 32*1024)
+	for pos := tagLen; ; {
+		if _, err := rw.Seek(pos, 0); err != nil {
+			return err
+		}
+		n, err := io.ReadFull(rw, buf)
+		if n > 0 {
+			if _, err := rw.Seek(pos-tagLen, 0); err != nil {
+				return err
+			}
+			if _, err := rw.Write(buf[:n]); err != nil {
+				return err
+			}
+			pos += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return t.Truncate(pos - tagLen)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Skips over the ID3v2 tag, including its footer, at the front of reader.
+// Nothing is consumed if reader doesn't start with a tag.
+func skipID3v2Tag(reader *bufio.Reader) error {
+	if !hasID3v2Tag(reader) {
+		return nil
+	}
+	header, err := parseID3v2Header(reader)
+	if err != nil {
+		return err
+	}
+	size := int(header.Size)
+	if header.Footer {
+		size += 10
+	}
+	return skipBytes(reader, size)
+}
+
+// NewTagReplacer returns a reader that yields tags encoded as an ID3v2.4 tag
+// followed by the contents of src with its existing ID3v2 tag, if any,
+// removed. The audio is streamed from src rather than buffered.
+func NewTagReplacer(src io.Reader, tags *SimpleTags) io.Reader {
+	return io.MultiReader(bytes.NewReader(encodeID3v2Tag(tags, WriteOptions{})), &tagSkipper{src: bufio.NewReader(src)})
+}
+
+// A reader that drops the front ID3v2 tag of src on the first call to Read.
+type tagSkipper struct {
+	src     *bufio.Reader
+	skipped bool
+}
+
+func (s *tagSkipper) Read(p []byte) (int, error) {
+	if !s.skipped {
+		s.skipped = true
+		if err := skipID3v2Tag(s.src); err != nil {
+			return 0, err
+		}
+	}
+	return s.src.Read(p)
+}
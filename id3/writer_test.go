@@ -0,0 +1,561 @@
+// Copyright 2011 Andrew Scherkus
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package id3
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTagReplacer(t *testing.T) {
+	audio := []byte("\xff\xfbAUDIO DATA")
+	tagged := append(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Old"))), audio...)
+	tags := &SimpleTags{
+		Title:    "New Title",
+		Artist:   "Björk",
+		Comments: []Comment{{"eng", "", "A comment"}},
+	}
+
+	for _, src := range [][]byte{tagged, audio} {
+		out, err := ioutil.ReadAll(NewTagReplacer(bytes.NewReader(src), tags))
+		if err != nil {
+			t.Fatalf("ReadAll: %s", err)
+		}
+
+		f, err := Read(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if f.Title != tags.Title || f.Artist != tags.Artist {
+			t.Errorf("expected '%s'/'%s' got '%s'/'%s'", tags.Title, tags.Artist, f.Title, f.Artist)
+		}
+		if len(f.Comments) != 1 || f.Comments[0] != tags.Comments[0] {
+			t.Errorf("Comments: expected %+v got %+v", tags.Comments, f.Comments)
+		}
+		if rest := out[10+f.Header.Size:]; !bytes.Equal(rest, audio) {
+			t.Errorf("audio: expected %q got %q", audio, rest)
+		}
+	}
+}
+
+func TestWriteOriginalReleaseAndPublisher(t *testing.T) {
+	tags := &SimpleTags{
+		Title:           "Paranoid Android",
+		Publisher:       "Parlophone",
+		OriginalRelease: time.Date(1997, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tag := encodeID3v2Tag(tags, WriteOptions{Version: 4})
+
+	text, err := ReadFile(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("ReadFile: %s", err)
+	}
+	if text["originalrelease"] != "1997" {
+		t.Errorf("TDOR: expected '1997' got '%s'", text["originalrelease"])
+	}
+
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Publisher != tags.Publisher {
+		t.Errorf("Publisher: expected '%s' got '%s'", tags.Publisher, f.Publisher)
+	}
+	if !f.OriginalRelease.Equal(tags.OriginalRelease) {
+		t.Errorf("OriginalRelease: expected %s got %s", tags.OriginalRelease, f.OriginalRelease)
+	}
+}
+
+func TestFormatID3v2Timestamp(t *testing.T) {
+	tests := map[string]time.Time{
+		"1997":                time.Date(1997, time.January, 1, 0, 0, 0, 0, time.UTC),
+		"1997-05":             time.Date(1997, time.May, 1, 0, 0, 0, 0, time.UTC),
+		"1997-05-21":          time.Date(1997, time.May, 21, 0, 0, 0, 0, time.UTC),
+		"1997-05-21T10:30":    time.Date(1997, time.May, 21, 10, 30, 0, 0, time.UTC),
+		"1997-05-21T10:30:15": time.Date(1997, time.May, 21, 10, 30, 15, 0, time.UTC),
+	}
+	for expected, ts := range tests {
+		if s := formatID3v2Timestamp(ts); s != expected {
+			t.Errorf("expected '%s' got '%s'", expected, s)
+		}
+		parsed, err := parseID3v2Timestamp(expected)
+		if err != nil || !parsed.Equal(ts) {
+			t.Errorf("parse '%s': expected %s got %s (%v)", expected, ts, parsed, err)
+		}
+	}
+}
+
+func TestWriteMultipleArtists(t *testing.T) {
+	tags := &SimpleTags{Artists: []string{"Daft Punk", "Pharrell Williams"}}
+
+	f, err := Read(bytes.NewReader(encodeID3v2Tag(tags, WriteOptions{Version: 4})))
+	if err != nil {
+		t.Fatalf("Read v2.4: %s", err)
+	}
+	if !reflect.DeepEqual(f.Artists, tags.Artists) {
+		t.Errorf("v2.4 Artists: expected %q got %q", tags.Artists, f.Artists)
+	}
+
+	f, err = Read(bytes.NewReader(encodeID3v2Tag(tags, WriteOptions{Version: 3})))
+	if err != nil {
+		t.Fatalf("Read v2.3: %s", err)
+	}
+	if f.Artist != "Daft Punk/Pharrell Williams" {
+		t.Errorf("v2.3 Artist: expected 'Daft Punk/Pharrell Williams' got '%s'", f.Artist)
+	}
+}
+
+func TestWriteEditedArtist(t *testing.T) {
+	tag := buildID3v2Tag(4, buildID3v2Frame(4, "TPE1", []byte("\x03Daft Punk\x00Pharrell Williams")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	f.Artist = "Daft Punk/Nile Rodgers"
+
+	var b bytes.Buffer
+	if err := WriteTag(&b, f, WriteOptions{}); err != nil {
+		t.Fatalf("WriteTag: %s", err)
+	}
+	if f, err = Read(&b); err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Artist != "Daft Punk/Nile Rodgers" {
+		t.Errorf("expected the edited artist got '%s'", f.Artist)
+	}
+}
+
+func TestWriteEditedComment(t *testing.T) {
+	tag := buildID3v2Tag(4,
+		buildID3v2Frame(4, "COMM", []byte("\x03eng\x00Old comment")),
+		buildID3v2Frame(4, "COMM", []byte("\x03eng\x00Second comment")),
+		buildID3v2Frame(4, "COMM", []byte("\x03engiTunNORM\x00 00000180")))
+	f, err := Read(bytes.NewReader(tag))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	f.Comment = "New comment"
+
+	var b bytes.Buffer
+	if err := WriteTag(&b, f, WriteOptions{}); err != nil {
+		t.Fatalf("WriteTag: %s", err)
+	}
+	if f.Comments[0].Text != "Old comment" {
+		t.Errorf("WriteTag modified Comments: %+v", f.Comments)
+	}
+	if f, err = Read(&b); err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	expected := []Comment{
+		{"eng", "", "New comment"},
+		{"eng", "", "Second comment"},
+		{"eng", "iTunNORM", " 00000180"},
+	}
+	if f.Comment != "New comment" || !reflect.DeepEqual(f.Comments, expected) {
+		t.Errorf("expected %+v got %q, %+v", expected, f.Comment, f.Comments)
+	}
+}
+
+// An in-memory io.ReadWriteSeeker that grows on writes past its end.
+type memFile struct {
+	data []byte
+	pos  int64
+}
+
+func (m *memFile) Read(p []byte) (int, error) {
+	if m.pos >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[m.pos:])
+	m.pos += int64(n)
+	return n, nil
+}
+
+func (m *memFile) Write(p []byte) (int, error) {
+	if end := m.pos + int64(len(p)); end > int64(len(m.data)) {
+		m.data = append(m.data, make([]byte, end-int64(len(m.data)))...)
+	}
+	n := copy(m.data[m.pos:], p)
+	m.pos += int64(n)
+	return n, nil
+}
+
+func (m *memFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case 1:
+		offset += m.pos
+	case 2:
+		offset += int64(len(m.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	m.pos = offset
+	return offset, nil
+}
+
+func (m *memFile) Truncate(size int64) error {
+	m.data = m.data[:size]
+	return nil
+}
+
+func TestWriteBoth(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	tags := &SimpleTags{
+		Title:  "Paranoid Android",
+		Artist: "Radiohead",
+		Album:  "OK Computer",
+		Year:   "1997",
+		Track:  "2/12",
+		Genre:  "Alternative",
+	}
+
+	// an old tag with plenty of padding, and an untagged file
+	oldTag := padID3v2Tag(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Old"))), 1024)
+	tagged := append(append(oldTag, audio...), buildID3v1Tag("Old")...)
+
+	for _, src := range [][]byte{tagged, audio} {
+		m := &memFile{data: append([]byte{}, src...)}
+		if err := WriteBoth(m, tags); err != nil {
+			t.Fatalf("WriteBoth: %s", err)
+		}
+		if len(src) == len(tagged) && len(m.data) != len(tagged) {
+			t.Errorf("expected the padded tag to keep the length %d got %d", len(tagged), len(m.data))
+		}
+		size, _ := TagSize(bytes.NewReader(m.data))
+		if rest := m.data[size : len(m.data)-128]; !bytes.Equal(rest, audio) {
+			t.Errorf("audio: expected %d bytes got %d", len(audio), len(rest))
+		}
+
+		r := bytes.NewReader(m.data)
+		_, v2, err := parseID3v2File(bufio.NewReader(r), &Options{})
+		if err != nil {
+			t.Fatalf("parseID3v2File: %s", err)
+		}
+		v1, err := parseID3v1File(r, &Options{})
+		if err != nil {
+			t.Fatalf("parseID3v1File: %s", err)
+		}
+		v1["track"] += "/12"
+		for _, k := range []string{"title", "artist", "album", "year", "track", "genre"} {
+			if v1[k] != v2[k] {
+				t.Errorf("%s: ID3v1 '%s' doesn't match ID3v2 '%s'", k, v1[k], v2[k])
+			}
+		}
+	}
+}
+
+func TestWriteUnsynchronized(t *testing.T) {
+	for _, data := range []string{"\xff\xfb", "\xff\x00", "ab\xff", "\xff\xe0\xff\xff\x10", "no syncs"} {
+		u := applyUnsynchronization([]byte(data))
+		if r := removeUnsynchronization(u); string(r) != data {
+			t.Errorf("%q: expected a byte exact round trip got %q via %q", data, r, u)
+		}
+		for i := 0; i+1 < len(u); i++ {
+			if u[i] == 0xff && u[i+1] >= 0xe0 {
+				t.Errorf("%q: false sync left at %d of %q", data, i, u)
+			}
+		}
+	}
+
+	// "ÿ" is 0xFF in ISO-8859-1 and the UTF-16 BOM is 0xFF 0xFE.
+	tags := &SimpleTags{Title: "ÿé", Artist: "Björk Guðmundsdóttir 日本"}
+	plain := encodeID3v2Tag(tags, WriteOptions{Version: 3})
+	tag := encodeID3v2Tag(tags, WriteOptions{Version: 3, Unsynchronize: true})
+	if tag[5]&(1<<7) == 0 {
+		t.Errorf("expected the unsynchronization flag to be set")
+	}
+	if body := removeUnsynchronization(tag[10:]); !bytes.Equal(body, plain[10:]) {
+		t.Errorf("expected %q got %q", plain[10:], body)
+	}
+
+	// UTF-8 never contains 0xFF so ID3v2.4 text frames are left alone.
+	tag = encodeID3v2Tag(tags, WriteOptions{Unsynchronize: true})
+	if plain := encodeID3v2Tag(tags, WriteOptions{}); !bytes.Equal(tag, plain) {
+		t.Errorf("expected %q got %q", plain, tag)
+	}
+}
+
+func TestWriteUnsynchronizedRoundTrip(t *testing.T) {
+	// "ÿà" is 0xFF 0xE0 in ISO-8859-1, a false sync in an ID3v2.3 title,
+	// and the picture holds one in either version.
+	tags := &SimpleTags{
+		Title:    "ÿà",
+		Artist:   "Björk",
+		Pictures: []Picture{{"image/jpeg", PictureTypeFrontCover, "", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\xff")}},
+	}
+	for _, version := range []int{3, 4} {
+		if plain := encodeID3v2Tag(tags, WriteOptions{Version: version}); !bytes.Contains(plain, []byte{0xff, 0xe0}) {
+			t.Fatalf("v2.%d: expected a false sync without unsynchronization", version)
+		}
+		var b bytes.Buffer
+		if err := WriteTag(&b, tags, WriteOptions{Version: version, Unsynchronize: true}); err != nil {
+			t.Fatalf("v2.%d: WriteTag: %s", version, err)
+		}
+		if bytes.Contains(b.Bytes(), []byte{0xff, 0xe0}) {
+			t.Errorf("v2.%d: false sync left in %q", version, b.Bytes())
+		}
+
+		f, err := Read(bytes.NewReader(b.Bytes()))
+		if err != nil {
+			t.Fatalf("v2.%d: Read: %s", version, err)
+		}
+		if f.Title != tags.Title || f.Artist != tags.Artist {
+			t.Errorf("v2.%d: expected %q, %q got %q, %q", version, tags.Title, tags.Artist, f.Title, f.Artist)
+		}
+		if !reflect.DeepEqual(f.Pictures, tags.Pictures) {
+			t.Errorf("v2.%d: expected %+v got %+v", version, tags.Pictures, f.Pictures)
+		}
+	}
+}
+
+func TestStripID3v2(t *testing.T) {
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 16*1024)
+	v1 := buildID3v1Tag("V1 Title")
+	tag := addID3v2Footer(buildID3v2Tag(4, buildID3v2Frame(4, "TIT2", []byte("\x03Title"))))
+	m := &memFile{data: append(append(tag, audio...), v1...)}
+
+	if err := StripID3v2(m); err != nil {
+		t.Fatalf("StripID3v2: %s", err)
+	}
+	if expected := append(append([]byte{}, audio...), v1...); !bytes.Equal(m.data, expected) {
+		t.Errorf("expected %d bytes of audio and ID3v1 tag got %d bytes", len(expected), len(m.data))
+	}
+
+	// without a tag there's nothing to do
+	if err := StripID3v2(m); err != nil {
+		t.Fatalf("StripID3v2: %s", err)
+	}
+	if len(m.data) != len(audio)+128 {
+		t.Errorf("expected %d bytes got %d", len(audio)+128, len(m.data))
+	}
+}
+
+func TestEncodedSize(t *testing.T) {
+	tags := &SimpleTags{
+		Title:    "Jóga",
+		Artist:   "Björk",
+		Year:     "1997-09-22",
+		Comments: []Comment{{"eng", "", "日本"}},
+		Pictures: []Picture{{MIMEType: "image/png", Data: make([]byte, 1000)}},
+	}
+	for _, version := range []int{3, 4} {
+		var b bytes.Buffer
+		WriteTag(&b, tags, WriteOptions{Version: version})
+		if size := EncodedSize(tags, WriteOptions{Version: version}); size != b.Len() {
+			t.Errorf("v2.%d: expected %d got %d", version, b.Len(), size)
+		}
+		if size, _ := TagSize(&b); int(size) != EncodedSize(tags, WriteOptions{Version: version}) {
+			t.Errorf("v2.%d: expected TagSize to agree got %d", version, size)
+		}
+	}
+
+	// options that change what is written change the size too; the title
+	// is 0xFF 0xE0 in ISO-8859-1 and the picture ends in 0xFF
+	unsynced := &SimpleTags{
+		Title:    "ÿà",
+		Artist:   "Björk",
+		Pictures: []Picture{{"image/jpeg", PictureTypeFrontCover, "", []byte("\xff\xd8\xff\xe0\x00\x10JFIF\xff\x00\xff")}},
+	}
+	for _, opts := range []WriteOptions{
+		{},
+		{Version: 3, Unsynchronize: true},
+		{Version: 4, Unsynchronize: true},
+		{Version: 3, Include: []string{"TIT2", "APIC"}},
+		{Version: 4, Exclude: []string{"APIC"}},
+		{Version: 3, Unsynchronize: true, Exclude: []string{"TIT2"}},
+	} {
+		if size, expected := EncodedSize(unsynced, opts), len(encodeID3v2Tag(unsynced, opts)); size != expected {
+			t.Errorf("%+v: expected %d got %d", opts, expected, size)
+		}
+	}
+
+	// the picture must not be copied to measure the tag
+	tags.Pictures[0].Data = make([]byte, 4<<20)
+	for _, opts := range []WriteOptions{{}, {Version: 3, Unsynchronize: true}} {
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		size := EncodedSize(tags, opts)
+		runtime.ReadMemStats(&after)
+		if expected := len(encodeID3v2Tag(tags, opts)); size != expected {
+			t.Errorf("large picture %+v: expected %d got %d", opts, expected, size)
+		}
+		if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+			t.Errorf("large picture %+v: %d bytes allocated", opts, allocated)
+		}
+	}
+}
+
+func TestWriteIncludeExclude(t *testing.T) {
+	tags := &SimpleTags{
+		Title:    "Title",
+		Artist:   "Artist",
+		Year:     "1997",
+		Comments: []Comment{{"eng", "", "A comment"}, {"eng", "iTunNORM", " 00000180"}},
+	}
+
+	ids := func(opts WriteOptions) []string {
+		_, frames, err := ReadAllFrames(bytes.NewReader(encodeID3v2Tag(tags, opts)))
+		if err != nil {
+			t.Fatalf("ReadAllFrames: %s", err)
+		}
+		var ids []string
+		for _, f := range frames {
+			ids = append(ids, f.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		opts     WriteOptions
+		expected []string
+	}{
+		{WriteOptions{}, []string{"TIT2", "TPE1", "TDRC", "COMM", "COMM"}},
+		{WriteOptions{Exclude: []string{"COMM"}}, []string{"TIT2", "TPE1", "TDRC"}},
+		{WriteOptions{Include: []string{"TIT2", "TYER"}, Version: 3}, []string{"TIT2", "TYER"}},
+		{WriteOptions{Include: []string{"TIT2", "COMM"}, Exclude: []string{"COMM"}}, []string{"TIT2"}},
+	}
+	for _, test := range tests {
+		if got := ids(test.opts); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%+v: expected %q got %q", test.opts, test.expected, got)
+		}
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	keys := []string{"title", "artist", "album", "year", "track", "disc", "genre", "length"}
+	audio := bytes.Repeat([]byte{0xff, 0xfb, 0x90, 0x00}, 64)
+	mp3, err := ioutil.ReadFile(path.Join("..", "test", "test_230.mp3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, src := range [][]byte{mp3, append(buildID3v2Tag(3, buildID3v2Frame(3, "TIT2", []byte("\x00Title"))), audio...)} {
+		m := &memFile{data: append([]byte{}, src...)}
+		before, err := ReadFile(m)
+		if err != nil {
+			t.Fatalf("ReadFile: %s", err)
+		}
+		tags, err := Read(bytes.NewReader(src))
+		if err != nil {
+			t.Fatalf("Read: %s", err)
+		}
+		if err := WriteFile(m, tags); err != nil {
+			t.Fatalf("WriteFile: %s", err)
+		}
+
+		m.Seek(0, 0)
+		after, err := ReadFile(m)
+		if err != nil {
+			t.Fatalf("ReadFile: %s", err)
+		}
+		for _, k := range keys {
+			if before[k] != after[k] {
+				t.Errorf("%s: expected '%s' got '%s'", k, before[k], after[k])
+			}
+		}
+
+		oldLen, _ := TagSize(bytes.NewReader(src))
+		newLen, _ := TagSize(bytes.NewReader(m.data))
+		if !bytes.Equal(m.data[newLen:], src[oldLen:]) {
+			t.Errorf("expected the %d bytes after the tag to be kept", len(src)-int(oldLen))
+		}
+		if m.data[3] != 4 {
+			t.Errorf("expected an ID3v2.4 tag got v2.%d", m.data[3])
+		}
+	}
+
+	// an untagged stream gets a tag prepended
+	m := &memFile{data: append([]byte{}, audio...)}
+	if err := WriteFile(m, &SimpleTags{Title: "New"}); err != nil {
+		t.Fatalf("WriteFile: %s", err)
+	}
+	f, err := Read(bytes.NewReader(m.data))
+	if err != nil || f.Title != "New" {
+		t.Fatalf("expected 'New' got %v (%v)", f, err)
+	}
+	if size, _ := TagSize(bytes.NewReader(m.data)); !bytes.Equal(m.data[size:], audio) {
+		t.Errorf("expected the audio to follow the tag")
+	}
+}
+
+func TestWriteMinimal(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteMinimal(&b, "Synthesized Speech", "Narrator"); err != nil {
+		t.Fatalf("WriteMinimal: %s", err)
+	}
+	header, frames, err := ReadAllFrames(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadAllFrames: %s", err)
+	}
+	if header.Version != 4 || len(frames) != 2 || frames[0].ID != "TIT2" || frames[1].ID != "TPE1" {
+		t.Errorf("expected an ID3v2.4 tag with TIT2 and TPE1 got v2.%d with %+v", header.Version, frames)
+	}
+
+	f, err := Read(&b)
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if f.Title != "Synthesized Speech" || f.Artist != "Narrator" {
+		t.Errorf("expected 'Synthesized Speech', 'Narrator' got %q, %q", f.Title, f.Artist)
+	}
+}
+
+func TestWriteID3v1Report(t *testing.T) {
+	tags := &SimpleTags{
+		Title:   "A Title Far Too Long For ID3v1 Tags",
+		Artist:  "Björk 日本",
+		Album:   "Album",
+		Year:    "2009-05-01",
+		Track:   "5",
+		Comment: "A comment of exactly 29 bytes",
+	}
+	var buf bytes.Buffer
+	report, err := WriteID3v1Report(&buf, tags)
+	if err != nil {
+		t.Fatalf("WriteID3v1Report: %s", err)
+	}
+	expected := TruncationReport{
+		Truncated: []string{"title", "year", "comment"},
+		Lossy:     []string{"artist"},
+	}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("expected %+v got %+v", expected, report)
+	}
+
+	tag := buf.Bytes()
+	if len(tag) != 128 || string(tag[:3]) != "TAG" {
+		t.Fatalf("expected a 128 byte ID3v1 tag got %q", tag)
+	}
+	if title := string(tag[3:33]); title != tags.Title[:30] {
+		t.Errorf("expected title %q got %q", tags.Title[:30], title)
+	}
+	if artist := string(bytes.TrimRight(tag[33:63], "\x00")); artist != "Bj\xf6rk ??" {
+		t.Errorf("expected artist %q got %q", "Bj\xf6rk ??", artist)
+	}
+
+	report, _ = WriteID3v1Report(&buf, &SimpleTags{Title: "Short", Year: "2009"})
+	if report.Truncated != nil || report.Lossy != nil {
+		t.Errorf("expected an empty report got %+v", report)
+	}
+}
@@ -15,12 +15,23 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"mime"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/bobertlo/go-id3/id3"
 )
 
+var (
+	extractArt = flag.String("extract-art", "", "write embedded pictures to `DIR`")
+	jsonOutput = flag.Bool("json", false, "print each file's tags as a JSON object")
+)
+
 func dumpFile(path string) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -29,31 +40,92 @@ func dumpFile(path string) {
 	}
 	defer f.Close()
 
-	tags, err := id3.ReadFile(f)
+	tags, err := id3.Read(f)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "id3.Read(%s): %s\n", path, err)
 		return
 	}
 
+	var pictures []string
+	if *extractArt != "" {
+		for i, p := range tags.Pictures {
+			name := pictureFileName(path, i, p)
+			if err := ioutil.WriteFile(name, p.Data, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "ioutil.WriteFile(%s): %s\n", name, err)
+				continue
+			}
+			pictures = append(pictures, name)
+		}
+	}
+
+	if *jsonOutput {
+		b, err := json.Marshal(map[string]interface{}{"path": path, "tags": tags})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "json.Marshal(%s): %s\n", path, err)
+			return
+		}
+		fmt.Println(string(b))
+	} else {
+		printTags(path, tags, pictures)
+	}
+}
+
+// Prints tags in the human readable format, followed by the files that
+// pictures were extracted to.
+func printTags(path string, tags *id3.SimpleTags, pictures []string) {
 	fmt.Println(path)
-	fmt.Printf("Title\t%s\n", tags["title"])
-	fmt.Printf("Artist\t%s\n", tags["artist"])
-	fmt.Printf("Album\t%s\n", tags["album"])
-	fmt.Printf("Year\t%s\n", tags["year"])
-	fmt.Printf("Track\t%s\n", tags["track"])
-	fmt.Printf("Disc\t%s\n", tags["disc"])
-	fmt.Printf("Genre\t%s\n", tags["genre"])
-	fmt.Printf("Length\t%s\n", tags["length"])
+	fmt.Printf("Title\t%s\n", tags.Title)
+	fmt.Printf("Artist\t%s\n", tags.Artist)
+	fmt.Printf("Album\t%s\n", tags.Album)
+	fmt.Printf("Year\t%s\n", tags.Year)
+	fmt.Printf("Track\t%s\n", tags.Track)
+	fmt.Printf("Disc\t%s\n", tags.Disc)
+	fmt.Printf("Genre\t%s\n", tags.Genre)
+	fmt.Printf("Length\t%s\n", tags.Length)
+	for _, name := range pictures {
+		fmt.Printf("Picture\t%s\n", name)
+	}
 	fmt.Println()
 }
 
+// Names the file for the i'th picture of path after the audio file, the
+// picture type and its MIME type, e.g. "DIR/song-1-cover-front.jpg".
+func pictureFileName(path string, i int, p id3.Picture) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	kind := strings.Join(strings.FieldsFunc(strings.ToLower(id3.PictureTypeName(p.PictureType)), func(r rune) bool {
+		return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+	}), "-")
+
+	ext := ".bin"
+	switch p.MIMEType {
+	case "image/jpeg", "image/jpg":
+		ext = ".jpg"
+	default:
+		if exts, _ := mime.ExtensionsByType(p.MIMEType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return filepath.Join(*extractArt, fmt.Sprintf("%s-%d-%s%s", base, i+1, kind, ext))
+}
+
 func main() {
-	if len(os.Args) == 1 {
-		fmt.Printf("Usage: %s [FILE]...\n", os.Args[0])
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [-json] [-extract-art DIR] [FILE]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
 		return
 	}
 
-	for _, path := range os.Args[1:] {
+	if *extractArt != "" {
+		if err := os.MkdirAll(*extractArt, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "os.MkdirAll(%s): %s\n", *extractArt, err)
+			os.Exit(1)
+		}
+	}
+	for _, path := range flag.Args() {
 		dumpFile(path)
 	}
 }